@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvHeader names the columns of the records appended to CSV files
+var csvHeader = []string{"timestamp", "provider", "server", "download_mbps", "upload_mbps", "latency_ms", "client_ip", "isp"}
+
+// appendRecord is a single line appended to a JSON Lines file
+type appendRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	SpeedResults
+}
+
+// appendFormat returns the record format for path based on its extension
+func appendFormat(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return "csv", nil
+	case ".jsonl", ".ndjson":
+		return "jsonl", nil
+	default:
+		return "", fmt.Errorf("cannot append to %q, expected a .csv or .jsonl file", path)
+	}
+}
+
+// appendResults appends one record for the results to path. Each record is
+// written with a single write to a file opened in append mode, so concurrent
+// runs cannot interleave their records. A new CSV file starts with a header.
+func appendResults(path string, results SpeedResults, timestamp time.Time) error {
+	format, err := appendFormat(path)
+	if err != nil {
+		return err
+	}
+
+	var record, header bytes.Buffer
+	if format == "csv" {
+		if err := writeCSVRecord(&header, csvHeader); err != nil {
+			return err
+		}
+		if err := writeCSVRecord(&record, csvRecord(results, timestamp)); err != nil {
+			return err
+		}
+	} else {
+		line, err := json.Marshal(appendRecord{Timestamp: timestamp, SpeedResults: results})
+		if err != nil {
+			return err
+		}
+		record.Write(append(line, '\n'))
+	}
+
+	// Only the run that creates the file writes the header
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o644)
+	if err == nil {
+		header.Write(record.Bytes())
+		record = header
+	} else if errors.Is(err, os.ErrExist) {
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(record.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// csvRecord returns the CSV columns for the results, speeds are in Mbps
+func csvRecord(results SpeedResults, timestamp time.Time) []string {
+	return []string{
+		timestamp.Format(time.RFC3339),
+		results.Metadata.Provider,
+		results.Metadata.Server,
+		megabitsPerSec(results.Download),
+		megabitsPerSec(results.Upload),
+		strconv.FormatFloat(results.LatencyMs, 'f', 1, 64),
+		results.Metadata.ClientIP,
+		results.Metadata.ISP,
+	}
+}
+
+func writeCSVRecord(buf *bytes.Buffer, record []string) error {
+	w := csv.NewWriter(buf)
+	if err := w.Write(record); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,64 @@
+// Package card renders speed test results as a small image for sharing
+package card
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Row is a labelled value on the card
+type Row struct {
+	Label string
+	Value string
+}
+
+// Card is the content of a result card
+type Card struct {
+	Title string
+	Rows  []Row
+	// Footer lines are printed small below the rows, e.g. the ISP and time
+	Footer []string
+}
+
+// Colors of the card, after the fast.com palette
+var (
+	background = color.RGBA{0x14, 0x14, 0x14, 0xff}
+	accent     = color.RGBA{0xe5, 0x09, 0x14, 0xff}
+	labelColor = color.RGBA{0xa0, 0xa0, 0xa0, 0xff}
+	valueColor = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// Format returns the image format for path based on its extension
+func Format(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".png":
+		return "png", nil
+	case ".svg":
+		return "svg", nil
+	default:
+		return "", fmt.Errorf("cannot render a card to %q, expected a .png or .svg file", path)
+	}
+}
+
+// Write renders c to path in the format matching its extension
+func Write(path string, c Card) error {
+	format, err := Format(path)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	if format == "png" {
+		err = writePNG(&buffer, c)
+	} else {
+		err = writeSVG(&buffer, c)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buffer.Bytes(), 0o644)
+}
@@ -0,0 +1,114 @@
+package card
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Layout of the PNG card before scaling, the built in font is 7x13 pixels
+const (
+	pngWidth       = 240
+	pngPadding     = 12
+	pngTitleHeight = 24
+	pngRowHeight   = 18
+	pngFooterLine  = 15
+	// pngScale enlarges the card so the small font stays readable
+	pngScale = 2
+)
+
+func writePNG(w io.Writer, c Card) error {
+	height := pngTitleHeight + len(c.Rows)*pngRowHeight + len(c.Footer)*pngFooterLine + 2*pngPadding
+	img := image.NewRGBA(image.Rect(0, 0, pngWidth, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(pngPadding, pngPadding, pngWidth-pngPadding, pngPadding+3),
+		image.NewUniform(accent), image.Point{}, draw.Src)
+
+	drawText(img, c.Title, pngPadding, pngPadding+18, valueColor)
+	y := pngPadding + pngTitleHeight
+	for _, row := range c.Rows {
+		y += pngRowHeight
+		drawText(img, row.Label, pngPadding, y-4, labelColor)
+		drawText(img, row.Value, pngWidth-pngPadding-textWidth(row.Value), y-4, valueColor)
+	}
+	for _, line := range c.Footer {
+		y += pngFooterLine
+		drawText(img, line, pngPadding, y, labelColor)
+	}
+
+	return png.Encode(w, scale(img, pngScale))
+}
+
+// asciiFolds maps letters outside ASCII to the ASCII letters they are based on
+var asciiFolds = map[rune]string{}
+
+func init() {
+	for _, fold := range []struct{ from, to string }{
+		{"ÀÁÂÃÄÅĀĂĄ", "A"}, {"àáâãäåāăą", "a"}, {"ÇĆĈĊČ", "C"}, {"çćĉċč", "c"},
+		{"ÐĎĐ", "D"}, {"ðďđ", "d"}, {"ÈÉÊËĒĔĖĘĚ", "E"}, {"èéêëēĕėęě", "e"},
+		{"ĜĞĠĢ", "G"}, {"ĝğġģ", "g"}, {"ĤĦ", "H"}, {"ĥħ", "h"},
+		{"ÌÍÎÏĨĪĬĮİ", "I"}, {"ìíîïĩīĭįı", "i"}, {"Ĵ", "J"}, {"ĵ", "j"}, {"Ķ", "K"}, {"ķ", "k"},
+		{"ĹĻĽĿŁ", "L"}, {"ĺļľŀł", "l"}, {"ÑŃŅŇ", "N"}, {"ñńņň", "n"},
+		{"ÒÓÔÕÖØŌŎŐ", "O"}, {"òóôõöøōŏő", "o"}, {"ŔŖŘ", "R"}, {"ŕŗř", "r"},
+		{"ŚŜŞŠ", "S"}, {"śŝşš", "s"}, {"ŢŤŦ", "T"}, {"ţťŧ", "t"},
+		{"ÙÚÛÜŨŪŬŮŰŲ", "U"}, {"ùúûüũūŭůűų", "u"}, {"Ŵ", "W"}, {"ŵ", "w"},
+		{"ÝŶŸ", "Y"}, {"ýÿŷ", "y"}, {"ŹŻŽ", "Z"}, {"źżž", "z"},
+		{"Æ", "AE"}, {"æ", "ae"}, {"Œ", "OE"}, {"œ", "oe"}, {"ß", "ss"}, {"Þ", "Th"}, {"þ", "th"},
+		{"‐‑‒–—", "-"}, {"‘’", "'"}, {"“”", `"`},
+	} {
+		for _, r := range fold.from {
+			asciiFolds[r] = fold.to
+		}
+	}
+}
+
+// asciiText folds s to the ASCII the built in font can draw, e.g. Köln to
+// Koln. Characters without an ASCII equivalent are drawn as a question mark.
+func asciiText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch folded, ok := asciiFolds[r]; {
+		case r < 0x80:
+			b.WriteRune(r)
+		case ok:
+			b.WriteString(folded)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// drawText draws s with its baseline starting at x, y
+func drawText(img draw.Image, s string, x, y int, c color.Color) {
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	drawer.DrawString(asciiText(s))
+}
+
+func textWidth(s string) int {
+	return font.MeasureString(basicfont.Face7x13, asciiText(s)).Ceil()
+}
+
+// scale enlarges img by an integer factor without smoothing
+func scale(img *image.RGBA, factor int) *image.RGBA {
+	bounds := img.Bounds()
+	scaled := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*factor, bounds.Dy()*factor))
+	for y := 0; y < scaled.Bounds().Dy(); y++ {
+		for x := 0; x < scaled.Bounds().Dx(); x++ {
+			scaled.SetRGBA(x, y, img.RGBAAt(x/factor, y/factor))
+		}
+	}
+	return scaled
+}
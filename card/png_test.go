@@ -0,0 +1,21 @@
+package card
+
+import "testing"
+
+func TestASCIIText(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Telefónica", "Telefonica"},
+		{"Köln", "Koln"},
+		{"Straße – Łódź", "Strasse - Lodz"},
+		{"東京 10 ms", "?? 10 ms"},
+		{"412.5 Mbps", "412.5 Mbps"},
+	}
+	for _, tt := range tests {
+		if got := asciiText(tt.input); got != tt.want {
+			t.Errorf("asciiText(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
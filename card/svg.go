@@ -0,0 +1,57 @@
+package card
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// Layout of the SVG card in pixels
+const (
+	svgWidth       = 480
+	svgPadding     = 24
+	svgTitleHeight = 56
+	svgRowHeight   = 44
+	svgFooterLine  = 20
+)
+
+func writeSVG(w io.Writer, c Card) error {
+	height := svgTitleHeight + len(c.Rows)*svgRowHeight + len(c.Footer)*svgFooterLine + 2*svgPadding
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %[1]d %[2]d" font-family="Helvetica, Arial, sans-serif">`+"\n", svgWidth, height)
+	fmt.Fprintf(&b, `  <rect width="100%%" height="100%%" rx="12" fill="%s"/>`+"\n", hex(background))
+	fmt.Fprintf(&b, `  <rect width="%d" height="6" x="%d" y="%d" fill="%s"/>`+"\n", svgWidth-2*svgPadding, svgPadding, svgPadding, hex(accent))
+	fmt.Fprintf(&b, `  <text x="%d" y="%d" font-size="22" font-weight="bold" fill="%s">%s</text>`+"\n",
+		svgPadding, svgPadding+40, hex(valueColor), escape(c.Title))
+
+	y := svgPadding + svgTitleHeight
+	for _, row := range c.Rows {
+		y += svgRowHeight
+		fmt.Fprintf(&b, `  <text x="%d" y="%d" font-size="18" fill="%s">%s</text>`+"\n",
+			svgPadding, y-8, hex(labelColor), escape(row.Label))
+		fmt.Fprintf(&b, `  <text x="%d" y="%d" font-size="30" font-weight="bold" text-anchor="end" fill="%s">%s</text>`+"\n",
+			svgWidth-svgPadding, y-8, hex(valueColor), escape(row.Value))
+	}
+	for _, line := range c.Footer {
+		y += svgFooterLine
+		fmt.Fprintf(&b, `  <text x="%d" y="%d" font-size="13" fill="%s">%s</text>`+"\n",
+			svgPadding, y, hex(labelColor), escape(line))
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mikkelam/fast-cli/provider"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// BaseURL is the root of the Cloudflare speed test endpoints
+const BaseURL = "https://speed.cloudflare.com"
+
+// DownloadSize is the number of bytes requested per download
+const DownloadSize = 26214400 // 25 MB
+
+// RequestTimeout bounds the metadata request made before a test
+var RequestTimeout = 5 * time.Second
+
+// Provider hands out speed.cloudflare.com endpoints
+type Provider struct {
+	metadata provider.Metadata
+}
+
+type metaResponse struct {
+	ClientIP       string `json:"clientIp"`
+	ASN            int    `json:"asn"`
+	ASOrganization string `json:"asOrganization"`
+	Colo           string `json:"colo"`
+	City           string `json:"city"`
+	Country        string `json:"country"`
+}
+
+// NewProvider returns a Cloudflare provider
+func NewProvider() *Provider {
+	return &Provider{
+		metadata: provider.Metadata{Provider: "cloudflare"},
+	}
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return "cloudflare"
+}
+
+// SupportsUpload reports that Cloudflare accepts uploads
+func (p *Provider) SupportsUpload() bool {
+	return true
+}
+
+// Metadata returns the client and colo information reported by Cloudflare
+func (p *Provider) Metadata() provider.Metadata {
+	return p.metadata
+}
+
+// DownloadURL returns a download endpoint serving size bytes
+func (p *Provider) DownloadURL(target provider.Target, size int64) string {
+	return fmt.Sprintf("%s/__down?bytes=%d", BaseURL, size)
+}
+
+// GetTargets returns count download and upload endpoints. Cloudflare routes all of
+// them to the nearest colo, so they only differ in the connection used.
+func (p *Provider) GetTargets(ctx context.Context, count int) (targets []provider.Target, err error) {
+	meta, err := getMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.metadata.ClientIP = meta.ClientIP
+	p.metadata.ISP = meta.ASOrganization
+	p.metadata.Location = strings.Trim(fmt.Sprintf("%s, %s", meta.City, meta.Country), ", ")
+	p.metadata.Server = meta.Colo
+	slog.Debug("cloudflare meta", "colo", meta.Colo, "client", meta.ClientIP,
+		"asn", meta.ASN, "organization", meta.ASOrganization)
+
+	for i := 0; i < count; i++ {
+		targets = append(targets, provider.Target{
+			URL:       fmt.Sprintf("%s/__down?bytes=%d", BaseURL, DownloadSize),
+			UploadURL: fmt.Sprintf("%s/__up", BaseURL),
+			Location:  meta.Colo,
+		})
+	}
+	return
+}
+
+func getMeta(ctx context.Context) (meta metaResponse, err error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", BaseURL+"/meta", nil)
+	if err != nil {
+		return meta, err
+	}
+	client := &http.Client{Timeout: RequestTimeout}
+	resp, err := client.Do(request)
+	if err != nil {
+		return meta, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return meta, fmt.Errorf("cloudflare meta request failed: %s", resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&meta)
+	return
+}
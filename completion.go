@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+)
+
+// The completion scripts ask fast-cli itself for candidates through the
+// --generate-bash-completion flag, so they stay correct as flags are added.
+// A partially typed flag is replaced by a bare "-", which lists every flag and
+// leaves the filtering to the shell; unknown flags cannot be parsed while short
+// option handling is enabled.
+
+const bashCompletion = `# bash completion for fast-cli
+_fast_cli_init_completion() {
+  COMPREPLY=()
+  _get_comp_words_by_ref "$@" cur prev words cword
+}
+
+_fast_cli_completion() {
+  local cur opts words cword requestComp
+  COMPREPLY=()
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  if declare -F _init_completion >/dev/null 2>&1; then
+    _init_completion -n "=:" || return
+  else
+    _fast_cli_init_completion -n "=:" || return
+  fi
+  words=("${words[@]:0:$cword}")
+  if [[ "$cur" == "-"* ]]; then
+    requestComp="${words[*]} - --generate-bash-completion"
+  else
+    requestComp="${words[*]} --generate-bash-completion"
+  fi
+  opts=$(eval "${requestComp}" 2>/dev/null)
+  COMPREPLY=($(compgen -W "${opts}" -- ${cur}))
+  return 0
+}
+
+complete -o bashdefault -o default -o nospace -F _fast_cli_completion fast-cli
+`
+
+const zshCompletion = `#compdef fast-cli
+
+_fast_cli() {
+  local -a opts
+  local cur
+  cur=${words[-1]}
+  if [[ "$cur" == "-"* ]]; then
+    opts=("${(@f)$(${words[@]:0:#words[@]-1} - --generate-bash-completion)}")
+  else
+    opts=("${(@f)$(${words[@]:0:#words[@]-1} --generate-bash-completion)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  else
+    _files
+  fi
+}
+
+compdef _fast_cli fast-cli
+`
+
+const powershellCompletion = `Register-ArgumentCompleter -Native -CommandName fast-cli -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | ForEach-Object { $_.ToString() })
+    $arguments = @($words | Select-Object -Skip 1)
+    if ($wordToComplete -ne '') {
+        $arguments = @($arguments | Select-Object -SkipLast 1)
+    }
+    if ($wordToComplete -like '-*') {
+        $arguments += '-'
+    }
+    & $words[0] @arguments --generate-bash-completion | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
+
+func printCompletion(c *cli.Context) error {
+	switch shell := c.Args().First(); shell {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "powershell":
+		fmt.Print(powershellCompletion)
+	case "fish":
+		script, err := c.App.ToFishCompletion()
+		if err != nil {
+			return err
+		}
+		fmt.Print(script)
+	default:
+		return withCodef(codeInvalidArgument, "unsupported shell %q, expected bash, zsh, fish or powershell", shell)
+	}
+	return nil
+}
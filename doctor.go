@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli/v2"
+
+	"mikkelam/fast-cli/fast"
+	"mikkelam/fast-cli/utils"
+)
+
+// proxyVariables are the environment variables Go reads its proxy settings from
+var proxyVariables = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"}
+
+// runDoctor prints the environment and the outcome of the connectivity checks
+// as a block to paste into bug reports, or as a JSON object keyed by the
+// lowercased labels with --json. Checks keep running after a failure.
+func runDoctor(c *cli.Context) error {
+	if err := initApputils(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	checks := map[string]string{}
+	line := func(format string, a ...any) {
+		text := fmt.Sprintf(format, a...)
+		b.WriteString(text)
+		if label, value, ok := strings.Cut(strings.TrimSpace(text), ":"); ok {
+			checks[strings.ToLower(label)] = strings.TrimSpace(value)
+		}
+	}
+	field := func(label string, format string, a ...any) {
+		line("   %-9s "+format+"\n", append([]any{label + ":"}, a...)...)
+	}
+
+	field("Version", "%s", displayVersion)
+	field("Go", "%s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	field("Terminal", "%s", terminalSummary())
+
+	p, err := newProvider()
+	if err != nil {
+		return err
+	}
+	field("Provider", "%s", p.Name())
+	field("Proxy", "%s", proxySummary(apiURL(p)))
+
+	ctx := c.Context
+	if err := checkEndpoint(ctx, apiURL(p), line); err != nil {
+		field("Check", "%v", err)
+	}
+	if fastProvider, ok := p.(*fast.Provider); ok {
+		field("Token", "%s", tokenSummary(ctx, fastProvider))
+	}
+
+	start := time.Now()
+	lookupCtx, cancel := context.WithTimeout(ctx, startupTimeout)
+	targets, err := p.GetTargets(lookupCtx, candidateServers)
+	cancel()
+	if err != nil {
+		field("Servers", "failed after %.0f ms: %v", milliseconds(time.Since(start)), err)
+	} else {
+		field("Servers", "%d from the %s provider in %.0f ms", len(targets), p.Name(), milliseconds(time.Since(start)))
+		if err := probeTarget(ctx, newTestClient(), p, targets[0], line); err != nil {
+			field("Probe", "%v", err)
+		}
+	}
+
+	if jsonOutput {
+		utils.FprintJSON(os.Stdout, "%s\n", toJSON(checks))
+		return nil
+	}
+	utils.Printf("```\n%s```\n", b.String())
+	return nil
+}
+
+// tokenSummary describes the cached fast.com api token and scrapes a new one,
+// since a run with a cached token does not show whether scraping still works
+func tokenSummary(ctx context.Context, p *fast.Provider) string {
+	cache := "not cached"
+	switch age, ok := p.TokenAge(); {
+	case p.TokenCachePath == "":
+		cache = "cache disabled"
+	case ok && age > fast.TokenTTL:
+		cache = fmt.Sprintf("cached %s, expired", humanize.Time(time.Now().Add(-age)))
+	case ok:
+		cache = fmt.Sprintf("cached %s", humanize.Time(time.Now().Add(-age)))
+	}
+
+	start := time.Now()
+	lookupCtx, cancel := context.WithTimeout(ctx, startupTimeout)
+	defer cancel()
+	if err := p.ScrapeToken(lookupCtx); err != nil {
+		return fmt.Sprintf("%s, scraping failed after %.0f ms: %v", cache, milliseconds(time.Since(start)), err)
+	}
+	return fmt.Sprintf("%s, freshly scraped from fast.com in %.0f ms", cache, milliseconds(time.Since(start)))
+}
+
+// terminalSummary describes how fast-cli sees the terminal
+func terminalSummary() string {
+	tty := func(f *os.File) string {
+		if utils.IsTerminal(f) {
+			return "terminal"
+		}
+		return "redirected"
+	}
+	charset := "unicode"
+	if utils.AppConfig.ASCII {
+		charset = "ascii"
+	}
+	return fmt.Sprintf("stdout %s, stderr %s, TERM=%s, %s", tty(os.Stdout), tty(os.Stderr), os.Getenv("TERM"), charset)
+}
+
+// proxySummary lists the proxy settings and the proxy used for rawURL, with passwords redacted
+func proxySummary(rawURL string) string {
+	var settings []string
+	for _, name := range proxyVariables {
+		if value := os.Getenv(name); value != "" {
+			settings = append(settings, name+"="+redactURL(value))
+		}
+	}
+	if len(settings) == 0 {
+		return "none"
+	}
+
+	route := "direct"
+	if request, err := http.NewRequest(http.MethodGet, rawURL, nil); err == nil {
+		if proxy, err := http.ProxyFromEnvironment(request); err != nil {
+			route = err.Error()
+		} else if proxy != nil {
+			route = "via " + proxy.Redacted()
+		}
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(settings, " "), route)
+}
+
+// redactURL hides the password of a proxy URL
+func redactURL(value string) string {
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return value
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"mikkelam/fast-cli/utils"
+)
+
+// Error codes reported in JSON output
+const (
+	codeUnknown             = "unknown"
+	codeInvalidArgument     = "invalid_argument"
+	codeProviderUnavailable = "provider_unavailable"
+	codeUploadUnsupported   = "upload_unsupported"
+	codeDownloadFailed      = "download_failed"
+	codeUploadFailed        = "upload_failed"
+	codeLatencyFailed       = "latency_failed"
+	codeOutputFailed        = "output_failed"
+	codeBelowExpected       = "below_expected"
+	codeNetworkUnreachable  = "network_unreachable"
+	codeAborted             = "aborted"
+	codeCaptivePortal       = "captive_portal"
+	codeBelowWarning        = "below_warning"
+	codeBelowCritical       = "below_critical"
+	codeUpdateCheckFailed   = "update_check_failed"
+)
+
+// Exit codes, documented in the README and kept stable for scripts
+const (
+	exitOK                 = 0
+	exitError              = 1
+	exitInvalidArgument    = 2
+	exitProviderFailure    = 3
+	exitNetworkUnreachable = 4
+	exitBelowExpected      = 5
+	exitAborted            = 130
+)
+
+// exitCodes maps error codes to exit codes, other errors exit with exitError
+var exitCodes = map[string]int{
+	codeInvalidArgument:     exitInvalidArgument,
+	codeProviderUnavailable: exitProviderFailure,
+	codeNetworkUnreachable:  exitNetworkUnreachable,
+	codeCaptivePortal:       exitNetworkUnreachable,
+	codeBelowExpected:       exitBelowExpected,
+	codeAborted:             exitAborted,
+}
+
+// appError attaches a machine readable code to an error
+type appError struct {
+	code string
+	err  error
+}
+
+func (e *appError) Error() string {
+	return e.err.Error()
+}
+
+func (e *appError) Unwrap() error {
+	return e.err
+}
+
+// withCode wraps err with a machine readable code
+func withCode(code string, err error) error {
+	return &appError{code: code, err: err}
+}
+
+// withCodef formats an error and attaches a machine readable code to it
+func withCodef(code string, format string, a ...any) error {
+	return withCode(code, fmt.Errorf(format, a...))
+}
+
+// errorCode returns the code attached to err, or codeUnknown
+func errorCode(err error) string {
+	var appErr *appError
+	if errors.As(err, &appErr) {
+		return appErr.code
+	}
+	return codeUnknown
+}
+
+// exitCode returns the process exit code for err
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if code, ok := exitCodes[errorCode(err)]; ok {
+		return code
+	}
+	return exitError
+}
+
+// networkUnreachable reports whether err means no connection could be made at
+// all, as opposed to a server that answered with something unexpected
+func networkUnreachable(err error) bool {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	return errors.As(err, &dnsErr) || (errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// failureCode returns codeNetworkUnreachable for connection failures and code otherwise
+func failureCode(code string, err error) string {
+	if networkUnreachable(err) {
+		return codeNetworkUnreachable
+	}
+	return code
+}
+
+type ErrorDetails struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ErrorResult struct {
+	Error ErrorDetails `json:"error"`
+}
+
+// printError reports err as a JSON object in JSON mode and as plain text
+// otherwise. Once the JSON results were printed, errors go to stderr as text so
+// that stdout holds a single JSON document.
+func printError(err error) {
+	if outputFormat == formatNagios {
+		printNagiosError(err)
+		return
+	}
+	if utils.AppConfig.JsonOutput && !resultsPrinted {
+		utils.PrintJSON("%s\n", toJSON(ErrorResult{
+			Error: ErrorDetails{Code: errorCode(err), Message: err.Error()},
+		}))
+		return
+	}
+	utils.Errorln(err)
+}
+
+// printNagiosError reports err as plugin output unless the results, which
+// already state a failed threshold, were printed
+func printNagiosError(err error) {
+	switch {
+	case errorCode(err) == codeBelowWarning || errorCode(err) == codeBelowCritical:
+	case resultsPrinted:
+		utils.Errorln(err)
+	default:
+		fmt.Printf("%s - %s\n", nagiosStateNames[nagiosState(err)], err)
+	}
+}
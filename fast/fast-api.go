@@ -2,99 +2,314 @@ package fast
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"mikkelam/fast-cli/provider"
 	printer "mikkelam/fast-cli/utils"
 	"net/http"
 	"regexp"
+	"strings"
+	"time"
 )
 
-// UseHTTPS sets if HTTPS is used
-var UseHTTPS = true
+// MaxAttempts is the number of times a request to fast.com is tried before giving up
+var MaxAttempts = 4
 
-// GetUrls returns a list of urls to the fast api downloads
-func GetUrls(urlCount uint64) (urls []string, err error) {
-	token, err := getFastToken()
-	if err != nil {
-		return nil, err
+// InitialBackoff is the delay before the first retry, it doubles with every attempt
+var InitialBackoff = 500 * time.Millisecond
+
+// RequestTimeout bounds a single request to fast.com or its api
+var RequestTimeout = 5 * time.Second
+
+// statusError is returned for responses with a non successful status code
+type statusError struct {
+	url  string
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned %d %s", e.url, e.code, http.StatusText(e.code))
+}
+
+// temporary reports whether retrying the request may succeed
+func (e *statusError) temporary() bool {
+	return e.code == http.StatusTooManyRequests || e.code >= http.StatusInternalServerError
+}
+
+// Provider hands out Netflix CDN servers through the fast.com api
+type Provider struct {
+	// UseHTTPS sets if HTTPS is used
+	UseHTTPS bool
+	// TokenCachePath is where the api token is cached between runs, empty disables caching
+	TokenCachePath string
+	// BaseURL replaces fast.com and api.fast.com when set, e.g. by a server with recorded responses
+	BaseURL string
+
+	metadata provider.Metadata
+}
+
+type apiLocation struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+func (l apiLocation) String() string {
+	return strings.Trim(fmt.Sprintf("%s, %s", l.City, l.Country), ", ")
+}
+
+type apiResponse struct {
+	Client struct {
+		IP       string      `json:"ip"`
+		ASN      string      `json:"asn"`
+		ISP      string      `json:"isp"`
+		Location apiLocation `json:"location"`
+	} `json:"client"`
+	Targets []struct {
+		Name     string      `json:"name"`
+		URL      string      `json:"url"`
+		Location apiLocation `json:"location"`
+	} `json:"targets"`
+}
+
+// NewProvider returns a fast.com provider
+func NewProvider(useHTTPS bool) *Provider {
+	return &Provider{
+		UseHTTPS:       useHTTPS,
+		TokenCachePath: tokenCachePath(),
+		metadata:       provider.Metadata{Provider: "fast"},
 	}
+}
 
-	httpProtocol := "https"
-	if !UseHTTPS {
-		httpProtocol = "http"
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return "fast"
+}
+
+// SupportsUpload reports that fast.com servers accept uploads
+func (p *Provider) SupportsUpload() bool {
+	return true
+}
+
+// Metadata returns the client information reported by the fast.com api
+func (p *Provider) Metadata() provider.Metadata {
+	return p.metadata
+}
+
+// GetTargets returns a list of fast.com servers, falling back to the default url if the api lists none
+func (p *Provider) GetTargets(ctx context.Context, count int) (targets []provider.Target, err error) {
+	token, cached := loadCachedToken(p.TokenCachePath)
+	if !cached {
+		if token, err = p.refreshToken(ctx); err != nil {
+			return nil, err
+		}
 	}
 
-	url := fmt.Sprintf("%s://api.fast.com/netflix/speedtest?https=%t&token=%s&urlCount=%d",
-		httpProtocol, UseHTTPS, token, urlCount)
-	printer.Debugln(fmt.Sprintf("getting download urls from %s", url))
+	jsonData, err := getPage(ctx, p.apiURL(token, count))
+
+	// A rejected cached token is the only reason to scrape fast.com again
+	var statusErr *statusError
+	if cached && errors.As(err, &statusErr) && statusErr.code == http.StatusForbidden {
+		slog.Debug("cached fast api token was rejected, fetching a new one")
+		removeCachedToken(p.TokenCachePath)
+		if token, err = p.refreshToken(ctx); err != nil {
+			return nil, err
+		}
+		jsonData, err = getPage(ctx, p.apiURL(token, count))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("api.fast.com unreachable: %w", err)
+	}
 
-	jsonData, _ := getPage(url)
+	var response apiResponse
+	if err := json.Unmarshal([]byte(jsonData), &response); err != nil {
+		return nil, fmt.Errorf("could not parse fast api response: %w", err)
+	}
 
-	re := regexp.MustCompile("(?U)\"url\":\"(.*)\"")
-	reUrls := re.FindAllStringSubmatch(jsonData, -1)
+	p.metadata.ClientIP = response.Client.IP
+	p.metadata.ISP = response.Client.ISP
+	p.metadata.Location = response.Client.Location.String()
 
-	printer.Debugln("urls:")
-	for _, arr := range reUrls {
-		urls = append(urls, arr[1])
-		printer.Debugln(fmt.Sprintf(" - %s", arr[1]))
+	for _, target := range response.Targets {
+		targets = append(targets, provider.Target{
+			URL:       target.URL,
+			UploadURL: target.URL,
+			Location:  target.Location.String(),
+		})
 	}
 
+	if len(targets) == 0 {
+		printer.Statusln("Using fallback endpoint")
+		targets = append(targets, provider.Target{URL: p.GetDefaultURL(), UploadURL: p.GetDefaultURL()})
+	}
 	return
 }
 
-// GetDefaultURL returns the fallback download URL
-func GetDefaultURL() (url string) {
-	httpProtocol := "https"
-	if !UseHTTPS {
-		httpProtocol = "http"
+// popPattern matches Netflix Open Connect hostnames such as
+// ipv4-c012-cph001-ix.1.oca.nflxvideo.net, capturing the POP code cph001
+var popPattern = regexp.MustCompile(`^ipv[46]-c\d+-([a-z]{3}\d{3})-`)
+
+// POP returns the code of the CDN point of presence embedded in a fast.com
+// server hostname, or an empty string for other hostnames
+func POP(host string) string {
+	if match := popPattern.FindStringSubmatch(host); match != nil {
+		return match[1]
 	}
-	url = fmt.Sprintf("%s://api.fast.com/netflix/speedtest", httpProtocol)
+	return ""
+}
+
+// DownloadURL returns a ranged URL on the target server, as used by fast.com itself
+func (p *Provider) DownloadURL(target provider.Target, size int64) string {
+	if target.URL == p.GetDefaultURL() {
+		return target.URL
+	}
+	return provider.RangeURL(target.URL, size)
+}
+
+// GetDefaultURL returns the fallback download URL
+func (p *Provider) GetDefaultURL() (url string) {
+	url = fmt.Sprintf("%s/netflix/speedtest", p.apiBaseURL())
 	return
 }
 
-func getFastToken() (token string, err error) {
-	baseURL := "https://fast.com"
-	if !UseHTTPS {
-		baseURL = "http://fast.com"
+func (p *Provider) apiURL(token string, count int) string {
+	url := fmt.Sprintf("%s/netflix/speedtest/v2?https=%t&token=%s&urlCount=%d",
+		p.apiBaseURL(), p.UseHTTPS, token, count)
+	slog.Debug("getting download urls", "url", url)
+	return url
+}
+
+// refreshToken scrapes a new api token from fast.com and caches it
+func (p *Provider) refreshToken(ctx context.Context) (token string, err error) {
+	token, err = p.getFastToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	saveCachedToken(p.TokenCachePath, token)
+	return token, nil
+}
+
+// TokenAge returns how long ago the cached api token was scraped, ok is false
+// without a cached token. The token may be older than TokenTTL.
+func (p *Provider) TokenAge() (age time.Duration, ok bool) {
+	cached, ok := readCachedToken(p.TokenCachePath)
+	if !ok {
+		return 0, false
+	}
+	return time.Since(cached.FetchedAt), true
+}
+
+// ScrapeToken scrapes a new api token from fast.com regardless of the cached one
+func (p *Provider) ScrapeToken(ctx context.Context) error {
+	_, err := p.refreshToken(ctx)
+	return err
+}
+
+func (p *Provider) protocol() string {
+	if !p.UseHTTPS {
+		return "http"
+	}
+	return "https"
+}
+
+// webBaseURL returns the URL of the fast.com website
+func (p *Provider) webBaseURL() string {
+	if p.BaseURL != "" {
+		return p.BaseURL
+	}
+	return fmt.Sprintf("%s://fast.com", p.protocol())
+}
+
+// apiBaseURL returns the URL of the fast.com api
+func (p *Provider) apiBaseURL() string {
+	if p.BaseURL != "" {
+		return p.BaseURL
+	}
+	return fmt.Sprintf("%s://api.fast.com", p.protocol())
+}
+
+func (p *Provider) getFastToken(ctx context.Context) (token string, err error) {
+	baseURL := p.webBaseURL()
+	fastBody, err := getPage(ctx, baseURL)
+	if err != nil {
+		return "", fmt.Errorf("fast.com unreachable: %w", err)
 	}
-	fastBody, _ := getPage(baseURL)
 
 	// Extract the app script url
 	re := regexp.MustCompile(`app-.*\.js`)
 	scriptNames := re.FindAllString(fastBody, 1)
+	if len(scriptNames) == 0 {
+		return "", errors.New("could not find the app script on fast.com, the page layout may have changed")
+	}
 
 	scriptURL := fmt.Sprintf("%s/%s", baseURL, scriptNames[0])
-	printer.Debugln(fmt.Sprintf("trying to get fast api token from %s", scriptURL))
+	slog.Debug("trying to get fast api token", "url", scriptURL)
 
 	// Extract the token
-	scriptBody, _ := getPage(scriptURL)
+	scriptBody, err := getPage(ctx, scriptURL)
+	if err != nil {
+		return "", fmt.Errorf("fast.com app script unreachable: %w", err)
+	}
 
 	re = regexp.MustCompile("token:\"[[:alpha:]]*\"")
 	tokens := re.FindAllString(scriptBody, 1)
 
 	if len(tokens) > 0 {
 		token = tokens[0][7 : len(tokens[0])-1]
-		printer.Debugln(fmt.Sprintf("found token %s", token))
+		slog.Debug("found fast api token", "token", token)
 	} else {
-		err := errors.New("could not find fast api token")
-		printer.Debugln(err)
-
+		err = errors.New("could not find fast api token")
+		slog.Debug(err.Error())
 	}
 	return token, err
 }
 
-func getPage(url string) (contents string, err error) {
+// getPage fetches url, retrying transient failures with exponential backoff
+func getPage(ctx context.Context, url string) (contents string, err error) {
+	backoff := InitialBackoff
+	for attempt := 1; ; attempt++ {
+		contents, err = fetchPage(ctx, url)
+
+		var statusErr *statusError
+		if err == nil || attempt >= MaxAttempts || ctx.Err() != nil ||
+			(errors.As(err, &statusErr) && !statusErr.temporary()) {
+			return contents, err
+		}
+
+		slog.Debug("request failed, retrying", "url", url, "attempt", attempt,
+			"max_attempts", MaxAttempts, "backoff", backoff, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return contents, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func fetchPage(ctx context.Context, url string) (contents string, err error) {
 	// Create the string buffer
 	buffer := bytes.NewBuffer(nil)
 
 	// Get the data
-	resp, err := http.Get(url)
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return contents, err
+	}
+	client := &http.Client{Timeout: RequestTimeout}
+	resp, err := client.Do(request)
 	if err != nil {
 		return contents, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return contents, &statusError{url: url, code: resp.StatusCode}
+	}
+
 	// Writer the body to file
 	_, err = io.Copy(buffer, resp.Body)
 	if err != nil {
@@ -0,0 +1,86 @@
+package fast
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TokenTTL is how long a cached fast api token is used before fast.com is scraped again
+var TokenTTL = 24 * time.Hour
+
+type cachedToken struct {
+	Token     string    `json:"token"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// tokenCachePath returns the file the api token is cached in, or an empty string
+// if the platform has no user cache directory
+func tokenCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "fast-cli", "token.json")
+}
+
+// readCachedToken returns the cached token, expired or not
+func readCachedToken(path string) (cached cachedToken, ok bool) {
+	if path == "" {
+		return cached, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cached, false
+	}
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Token == "" {
+		return cached, false
+	}
+	return cached, true
+}
+
+// loadCachedToken returns the cached token if it exists and has not expired
+func loadCachedToken(path string) (token string, ok bool) {
+	cached, ok := readCachedToken(path)
+	if !ok {
+		return "", false
+	}
+	if time.Since(cached.FetchedAt) > TokenTTL {
+		slog.Debug("cached fast api token has expired", "fetched_at", cached.FetchedAt)
+		return "", false
+	}
+
+	slog.Debug("using cached fast api token", "path", path)
+	return cached.Token, true
+}
+
+// saveCachedToken stores the token, failures are not fatal and only logged
+func saveCachedToken(path string, token string) {
+	if path == "" {
+		return
+	}
+
+	data, err := json.Marshal(cachedToken{Token: token, FetchedAt: time.Now().UTC()})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o600)
+	}
+	if err != nil {
+		slog.Warn("could not cache fast api token", "path", path, "error", err)
+	}
+}
+
+// removeCachedToken drops a token that fast.com no longer accepts
+func removeCachedToken(path string) {
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		slog.Warn("could not remove cached fast api token", "path", path, "error", err)
+	}
+}
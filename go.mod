@@ -5,6 +5,9 @@ go 1.22.4
 require (
 	github.com/dustin/go-humanize v1.0.1
 	github.com/urfave/cli/v2 v2.27.2
+	golang.org/x/image v0.18.0
+	golang.org/x/sys v0.22.0
+	golang.org/x/term v0.22.0
 )
 
 require (
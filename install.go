@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"mikkelam/fast-cli/service"
+	"mikkelam/fast-cli/utils"
+)
+
+// installService schedules fast-cli with the arguments after "--" to run every --interval
+func installService(c *cli.Context) error {
+	if err := initApputils(); err != nil {
+		return err
+	}
+
+	executable, err := os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	if err != nil {
+		return withCodef(codeUnknown, "could not find the fast-cli executable: %w", err)
+	}
+
+	spec := service.Spec{
+		Name:       c.String("name"),
+		Executable: executable,
+		Args:       c.Args().Slice(),
+		Interval:   c.Duration("interval"),
+	}
+	plan, err := service.NewPlan(spec)
+	if err != nil {
+		return withCode(codeInvalidArgument, err)
+	}
+
+	if c.Bool("dry-run") {
+		utils.Printf("%s", plan)
+		return nil
+	}
+	if err := service.Install(plan); err != nil {
+		return withCodef(codeUnknown, "could not install the service: %w", err)
+	}
+	utils.Printf("Installed %s, running %s every %s\n", spec.Name,
+		strings.TrimSpace("fast-cli "+strings.Join(spec.Args, " ")), spec.Interval)
+	utils.Println(plan.Note)
+	return nil
+}
+
+// installServiceFlags are the flags of the install-service command
+func installServiceFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "interval",
+			Value: time.Hour,
+			Usage: "How often the speed test runs",
+		},
+		&cli.StringFlag{
+			Name:  "name",
+			Value: "fast-cli",
+			Usage: "Name of the service or scheduled task",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Print the files and commands instead of installing them",
+		},
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"mikkelam/fast-cli/provider"
+)
+
+// latencyProbes is the number of connections opened per server when measuring latency
+const latencyProbes = 3
+
+// testServers is the number of servers tested against, chosen by latency from
+// candidateServers candidates
+const (
+	testServers      = 4
+	candidateServers = 8
+)
+
+// probeLatency returns the lowest TCP connect time to the host of rawURL.
+// The host is resolved once up front so DNS lookups do not count towards the latency.
+func probeLatency(ctx context.Context, rawURL string, probes int) (time.Duration, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, err
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+	if err != nil {
+		return 0, err
+	}
+	address := net.JoinHostPort(addrs[0], port)
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	var best time.Duration
+	for i := 0; i < probes; i++ {
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return 0, fmt.Errorf("failed to connect to %s: %w", u.Host, err)
+		}
+		rtt := time.Since(start)
+		conn.Close()
+
+		if best == 0 || rtt < best {
+			best = rtt
+		}
+	}
+	return best, nil
+}
+
+// selectTargets probes the latency to every candidate and returns the count
+// closest ones, or the farthest ones with worst, along with the lowest latency
+// among them. Unreachable candidates are dropped, unless none of them could be
+// reached, then the error is the last probe error.
+func selectTargets(ctx context.Context, candidates []provider.Target, count int, worst bool) ([]provider.Target, time.Duration, error) {
+	type probed struct {
+		target  provider.Target
+		latency time.Duration
+		err     error
+	}
+	results := make([]probed, len(candidates))
+	var wg sync.WaitGroup
+	for i, target := range candidates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			latency, err := probeLatency(ctx, target.URL, latencyProbes)
+			results[i] = probed{target, latency, err}
+		}()
+	}
+	wg.Wait()
+
+	var reachable []probed
+	var err error
+	for _, result := range results {
+		slog.Info("candidate server", "url", result.target.URL, "latency", result.latency, "error", result.err)
+		if result.err != nil {
+			err = result.err
+			continue
+		}
+		reachable = append(reachable, result)
+	}
+	if len(reachable) == 0 {
+		return candidates[:min(count, len(candidates))], 0, err
+	}
+
+	sort.SliceStable(reachable, func(i, j int) bool {
+		if worst {
+			return reachable[i].latency > reachable[j].latency
+		}
+		return reachable[i].latency < reachable[j].latency
+	})
+	var selected []provider.Target
+	var latency time.Duration
+	for _, result := range reachable[:min(count, len(reachable))] {
+		selected = append(selected, result.target)
+		if latency == 0 || result.latency < latency {
+			latency = result.latency
+		}
+	}
+	return selected, latency, nil
+}
@@ -2,14 +2,30 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"mikkelam/fast-cli/card"
+	"mikkelam/fast-cli/cloudflare"
 	"mikkelam/fast-cli/fast"
+	"mikkelam/fast-cli/mock"
+	"mikkelam/fast-cli/notify"
+	"mikkelam/fast-cli/provider"
+	"mikkelam/fast-cli/server"
+	"mikkelam/fast-cli/sse"
 	"mikkelam/fast-cli/utils"
 
 	"github.com/urfave/cli/v2"
@@ -18,12 +34,41 @@ import (
 type Speed struct {
 	Speed float64 `json:"speed"`
 	Unit  string  `json:"unit"`
+	// Consistency is the steadiness of the throughput in percent, see consistency
+	Consistency *float64 `json:"consistency,omitempty"`
+	// PercentOfExpected compares the speed to the plan speed given with --expected
+	PercentOfExpected *float64 `json:"percent_of_expected,omitempty"`
+	Errors            []string `json:"errors,omitempty"`
+
+	bitsPerSec float64
+	samples    []float64
+	bytes      uint64
+	elapsed    time.Duration
 }
 type SpeedResults struct {
-	Download Speed  `json:"download"`
-	Upload   *Speed `json:"upload"`
+	Download       *Speed            `json:"download"`
+	Upload         *Speed            `json:"upload"`
+	SingleDownload *Speed            `json:"single_download,omitempty"`
+	LatencyMs      float64           `json:"latency_ms,omitempty"`
+	Streaming      *Verdict          `json:"streaming,omitempty"`
+	Metadata       provider.Metadata `json:"metadata"`
+	Sweep          []SweepLevel      `json:"sweep,omitempty"`
+	Servers        []Server          `json:"servers,omitempty"`
+	Traces         []Trace           `json:"traces,omitempty"`
+
+	// timestamp is when the test started
+	timestamp time.Time
 }
 
+// sparklineWidth is the maximum width of the throughput sparkline in the summary
+const sparklineWidth = 40
+
+// startupTimeout bounds the time spent getting test urls from the provider
+const startupTimeout = 20 * time.Second
+
+// speedTestCategory groups the commands that share the speed test flags
+const speedTestCategory = "speed tests"
+
 var (
 	version        = "dev"
 	commit         = "dirty"
@@ -31,306 +76,1056 @@ var (
 	displayVersion string
 	notHTTPS       bool
 	simpleProgress bool
+	intervalOutput time.Duration
+	progressListen string
 	checkUpload    bool
-	maxDuration    time.Duration
+	uploadOnly     bool
+	maxDuration    = 4 * time.Second
+	requestTimeout = 10 * time.Second
 	jsonOutput     bool
+	outputFormat   = formatText
 	debugOutput    bool
+	logLevel       string
+	verbosity      int
+	logFile        string
+	logCloser      io.Closer
+	noColor        bool
+	locale         string
+	goodSpeed      = "100Mbps"
+	poorSpeed      = "25Mbps"
+	thresholds     utils.SpeedThresholds
+	tuiMode        bool
+	notifyDone     bool
+	outputFile     string
+	appendFile     string
+	shareFile      string
+	quietOutput    bool
+	userAgent      string
+	singleStream   bool
+	sweep          string
+	warningRate    string
+	criticalRate   string
+	sweepLevels    []int
+	noKeepAlive    bool
+	resultsPrinted bool
+	traceRoute     bool
+	worstServers   bool
+	preflight      bool
+	mockMode       bool
+	mockURL        string
+
+	expectedDownload     string
+	expectedUpload       string
+	expectedDownloadRate float64
+	expectedUploadRate   float64
+	minPercent           float64
+	customHeaders        headerFlag
+	requestHeaders       http.Header
+	limit                string
+	limitRate            float64
+	display              progressDisplay
+	customURLs           cli.StringSlice
+	providerName         = "fast"
+	listenAddr           string
 )
-var spinnerStates = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
-var spinnerIndex = 0
 
 func main() {
 	displayVersion = fmt.Sprintf("%s-%s (built %s)", version, commit, date)
+	userAgent = displayVersion
+	cli.VersionFlag = &cli.BoolFlag{
+		Name:  "version",
+		Usage: "print the version",
+	}
 	app := &cli.App{
-		Name:    "fast-cli",
-		Usage:   "Estimate connection speed using fast.com",
-		Version: displayVersion,
-		Flags: []cli.Flag{
+		Name:                   "fast-cli",
+		Usage:                  "Estimate connection speed using fast.com",
+		Version:                displayVersion,
+		UseShortOptionHandling: true,
+		EnableBashCompletion:   true,
+		Flags: append(testFlags(true),
 			&cli.BoolFlag{
-				Name:        "no-https",
-				Aliases:     []string{"n"},
-				Usage:       "Do not use HTTPS when connecting",
-				Destination: &notHTTPS,
+				Name:        "debug",
+				Aliases:     []string{"D"},
+				Usage:       "Write debug messages to console",
+				Destination: &debugOutput,
+				Hidden:      true,
 			},
 			&cli.BoolFlag{
-				Name:        "simple",
-				Aliases:     []string{"s"},
-				Usage:       "Only display the result, no dynamic progress bar",
-				Destination: &simpleProgress,
+				Name:    "verbose",
+				Aliases: []string{"v"},
+				Usage:   "Show servers, timings and per-stream details, repeat (-vv) for full request/response debugging",
+				Count:   &verbosity,
 			},
-			&cli.BoolFlag{
-				Name:        "upload",
-				Aliases:     []string{"u"},
-				Usage:       "Test upload speed as well",
-				Destination: &checkUpload,
+			&cli.StringFlag{
+				Name:        "log-level",
+				Value:       "warn",
+				Usage:       "Minimum level of diagnostic log messages: debug, info, warn or error",
+				Destination: &logLevel,
 			},
-			&cli.DurationFlag{
-				Name:        "max-duration",
-				Aliases:     []string{"d"},
-				DefaultText: "4s",
-				Value:       time.Second * 4,
-				Usage:       "Maximum duration for the speed test (e.g., 30s, 1m)",
-				Destination: &maxDuration,
+			&cli.StringFlag{
+				Name:        "log-file",
+				Usage:       "Write diagnostic logs as JSON to `FILE`, rotated when it grows past 10 MB",
+				Destination: &logFile,
 			},
-			&cli.BoolFlag{
-				Name:        "json",
-				Usage:       "Output in JSON format",
-				Destination: &jsonOutput,
+		),
+		Before: func(c *cli.Context) error {
+			// Flags given before a subcommand would otherwise be reset to their
+			// defaults when the subcommand parses its own copy of them
+			for _, cmd := range c.App.Commands {
+				if cmd.Category == speedTestCategory {
+					cmd.Flags = testFlags(cmd.Name == "test")
+				}
+			}
+			return nil
+		},
+		Action:       runTest,
+		OnUsageError: usageError,
+		Commands: []*cli.Command{
+			{
+				Name:     "test",
+				Usage:    "Measure latency, download and optionally upload speed (default)",
+				Category: speedTestCategory,
+				Flags:    testFlags(true),
+				Action:   runTest,
 			},
-			&cli.BoolFlag{
-				Name:        "debug",
-				Aliases:     []string{"D"},
-				Usage:       "Write debug messages to console",
-				Destination: &debugOutput,
-				Hidden:      true,
+			{
+				Name:     "download",
+				Usage:    "Measure latency and download speed",
+				Category: speedTestCategory,
+				Flags:    testFlags(false),
+				Action:   runDownload,
+			},
+			{
+				Name:     "upload",
+				Usage:    "Measure latency and upload speed",
+				Category: speedTestCategory,
+				Flags:    testFlags(false),
+				Action:   runUpload,
+			},
+			{
+				Name:     "latency",
+				Usage:    "Measure the unloaded latency to the test servers",
+				Category: speedTestCategory,
+				Flags:    testFlags(false),
+				Action:   runLatency,
+			},
+			{
+				Name:  "serve-target",
+				Usage: "Serve download and upload endpoints that other fast-cli instances can test against",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "listen",
+						Aliases:     []string{"l"},
+						Value:       ":8080",
+						Usage:       "Address to listen on",
+						Destination: &listenAddr,
+					},
+				},
+				Action: serveTarget,
+			},
+			{
+				Name:  "version",
+				Usage: "Print the version",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "check",
+						Aliases: []string{"check-update"},
+						Usage:   "Check GitHub for a newer release",
+					},
+				},
+				Action: printVersion,
+			},
+			{
+				Name:   "doctor",
+				Usage:  "Print versions, proxy settings and connectivity checks for bug reports",
+				Action: runDoctor,
+			},
+			{
+				Name:      "install-service",
+				Usage:     "Run fast-cli periodically with systemd, launchd or the Windows task scheduler",
+				ArgsUsage: "[-- fast-cli flags]",
+				Flags:     installServiceFlags(),
+				Action:    installService,
+			},
+			{
+				Name:      "completion",
+				Usage:     "Print a shell completion script",
+				ArgsUsage: "bash|zsh|fish|powershell",
+				Action:    printCompletion,
 			},
 		},
-		Action: run,
+	}
+	for _, cmd := range app.Commands {
+		cmd.OnUsageError = usageError
 	}
 
-	if err := app.Run(os.Args); err != nil {
-		utils.Println(err)
-		os.Exit(1)
+	// Ctrl+C cancels the running test, a second one terminates immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := app.RunContext(ctx, os.Args)
+	if logCloser != nil {
+		logCloser.Close()
+	}
+	if err != nil && ctx.Err() != nil && errorCode(err) != codeAborted {
+		err = withCodef(codeAborted, "test aborted")
 	}
+	if err != nil {
+		printError(err)
+	}
+	stop()
+	if outputFormat == formatNagios {
+		os.Exit(nagiosState(err))
+	}
+	os.Exit(exitCode(err))
 }
 
-func initApputils() {
-	utils.AppConfig.Debug = debugOutput
-	utils.AppConfig.JsonOutput = jsonOutput
-
-	if debugOutput {
-		utils.Debugln("Debug logging enabled")
+// usageError reports invalid flags as an invalid argument error, which main
+// prints once. The help is only shown with text output, so that JSON and
+// plugin output stay machine readable.
+func usageError(c *cli.Context, err error, isSubcommand bool) error {
+	utils.AppConfig.JsonOutput = jsonOutput || outputFormat == formatJSON || outputFormat == formatOoklaJSON
+	if !utils.AppConfig.JsonOutput && outputFormat != formatNagios {
+		if isSubcommand {
+			cli.ShowCommandHelp(c.Lineage()[1], c.Command.Name)
+		} else {
+			cli.ShowAppHelp(c)
+		}
+		fmt.Println()
 	}
+	return withCode(codeInvalidArgument, err)
+}
 
-	utils.Debugln("Using HTTPS")
-	if notHTTPS {
-		utils.Debugln("Not using HTTPS")
+// testFlags returns the flags shared by the speed test commands. Their defaults
+// are the current settings, so the flags can be built again once the global
+// flags have been parsed.
+func testFlags(withUpload bool) []cli.Flag {
+	flags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:        "no-https",
+			Aliases:     []string{"n"},
+			Usage:       "Do not use HTTPS when connecting",
+			Value:       notHTTPS,
+			Destination: &notHTTPS,
+		},
+		&cli.BoolFlag{
+			Name:        "simple",
+			Aliases:     []string{"s"},
+			Usage:       "Only display the result, no dynamic progress bar",
+			Value:       simpleProgress,
+			Destination: &simpleProgress,
+		},
+		&cli.DurationFlag{
+			Name:        "interval-output",
+			Usage:       "Print a timestamped line with the average speed so far every `INTERVAL`, e.g. 5s, implies --simple",
+			Value:       intervalOutput,
+			Destination: &intervalOutput,
+		},
+		&cli.StringFlag{
+			Name:        "progress-listen",
+			Usage:       "Stream progress events of the test as server-sent events from http://`ADDR`" + progressStreamPath + ", e.g. :9090",
+			Value:       progressListen,
+			Destination: &progressListen,
+		},
+		&cli.BoolFlag{
+			Name:        "tui",
+			Usage:       "Show a full screen view with live throughput graphs and per-stream details",
+			Value:       tuiMode,
+			Destination: &tuiMode,
+		},
+		&cli.BoolFlag{
+			Name:        "notify",
+			Usage:       "Show a desktop notification with the result when the test finishes",
+			Value:       notifyDone,
+			Destination: &notifyDone,
+		},
+		&cli.BoolFlag{
+			Name:        "single",
+			Usage:       "Also measure the download speed over a single connection, reported separately",
+			Value:       singleStream,
+			Destination: &singleStream,
+		},
+		&cli.StringFlag{
+			Name:        "sweep",
+			Usage:       "Measure the download speed with each of the given numbers of streams in turn, e.g. 1,2,4,8,16",
+			Value:       sweep,
+			Destination: &sweep,
+		},
+		&cli.BoolFlag{
+			Name:        "worst",
+			Usage:       "Test against the candidate servers with the highest instead of the lowest latency",
+			Value:       worstServers,
+			Destination: &worstServers,
+		},
+		&cli.BoolFlag{
+			Name:        "trace",
+			Usage:       "Trace the route to the test servers with the system traceroute after the test",
+			Value:       traceRoute,
+			Destination: &traceRoute,
+		},
+		&cli.BoolFlag{
+			Name:        "preflight",
+			Usage:       "Check DNS, TLS and a small download before the test, and detect captive portals",
+			Value:       preflight,
+			Destination: &preflight,
+		},
+		&cli.BoolFlag{
+			Name:        "mock",
+			Usage:       "Test against a local server with recorded fast.com responses and a fixed 100 Mbps, for development",
+			Value:       mockMode,
+			Destination: &mockMode,
+		},
+		&cli.DurationFlag{
+			Name:        "max-duration",
+			Aliases:     []string{"d"},
+			Value:       maxDuration,
+			Usage:       "Maximum duration for the speed test (e.g., 30s, 1m)",
+			Destination: &maxDuration,
+		},
+		&cli.DurationFlag{
+			Name:        "request-timeout",
+			Value:       requestTimeout,
+			Usage:       "Abort a test request that makes no progress for this long, 0 disables the timeout",
+			Destination: &requestTimeout,
+		},
+		&cli.StringFlag{
+			Name:        "user-agent",
+			Value:       userAgent,
+			Usage:       "User-Agent header sent with the test requests",
+			Destination: &userAgent,
+		},
+		&cli.GenericFlag{
+			Name:  "header",
+			Usage: "Add a `\"Key: Value\"` header to the test requests (repeatable)",
+			Value: &customHeaders,
+		},
+		&cli.BoolFlag{
+			Name:        "no-keepalive",
+			Usage:       "Open a new connection for every test request instead of reusing connections",
+			Value:       noKeepAlive,
+			Destination: &noKeepAlive,
+		},
+		&cli.StringFlag{
+			Name:        "format",
+			Usage:       "Output format: " + strings.Join(outputFormats, ", "),
+			Value:       outputFormat,
+			Destination: &outputFormat,
+		},
+		&cli.BoolFlag{
+			Name:        "json",
+			Usage:       "Output in JSON format, same as --format json",
+			Value:       jsonOutput,
+			Destination: &jsonOutput,
+		},
+		&cli.BoolFlag{
+			Name:        "quiet",
+			Aliases:     []string{"q"},
+			Usage:       "Only print the download speed in Mbps, followed by the upload speed with --upload",
+			Value:       quietOutput,
+			Destination: &quietOutput,
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Value:       outputFile,
+			Usage:       "Also write the final result, in the chosen format, to `FILE`",
+			Destination: &outputFile,
+		},
+		&cli.StringFlag{
+			Name:        "append",
+			Value:       appendFile,
+			Usage:       "Append one record per run to `FILE`, a .csv (created with a header) or .jsonl file",
+			Destination: &appendFile,
+		},
+		&cli.StringFlag{
+			Name:        "share",
+			Value:       shareFile,
+			Usage:       "Render the result as a shareable card to `FILE`, a .png or .svg image",
+			Destination: &shareFile,
+		},
+		&cli.BoolFlag{
+			Name:        "no-color",
+			Usage:       "Disable colored output",
+			Value:       noColor,
+			Destination: &noColor,
+		},
+		&cli.StringFlag{
+			Name:        "locale",
+			Value:       locale,
+			Usage:       "Write decimals in text output the way `LOCALE` does, e.g. de_DE or system, JSON is unaffected",
+			Destination: &locale,
+		},
+		&cli.StringFlag{
+			Name:        "limit",
+			Value:       limit,
+			Usage:       "Limit the combined throughput of the test streams to `RATE`, e.g. 200Mbps",
+			Destination: &limit,
+		},
+		&cli.StringFlag{
+			Name:        "expected",
+			Value:       expectedDownload,
+			Usage:       "Report the download speed as a percentage of your plan's `RATE`, e.g. 500Mbps",
+			Destination: &expectedDownload,
+		},
+		&cli.StringFlag{
+			Name:        "expected-upload",
+			Value:       expectedUpload,
+			Usage:       "Report the upload speed as a percentage of your plan's `RATE`",
+			Destination: &expectedUpload,
+		},
+		&cli.Float64Flag{
+			Name:        "min-percent",
+			Value:       minPercent,
+			Usage:       "Exit with an error when a speed is below this percentage of the expected speed",
+			Destination: &minPercent,
+		},
+		&cli.StringFlag{
+			Name:        "warning",
+			Aliases:     []string{"w"},
+			Value:       warningRate,
+			Usage:       "With --format nagios, report WARNING for speeds below `RATE`, or below DOWN,UP for separate download and upload rates",
+			Destination: &warningRate,
+		},
+		&cli.StringFlag{
+			Name:        "critical",
+			Aliases:     []string{"c"},
+			Value:       criticalRate,
+			Usage:       "With --format nagios, report CRITICAL for speeds below `RATE`, or below DOWN,UP",
+			Destination: &criticalRate,
+		},
+		&cli.StringFlag{
+			Name:        "good-speed",
+			Value:       goodSpeed,
+			Usage:       "Speeds at or above this rate are shown in green",
+			Destination: &goodSpeed,
+		},
+		&cli.StringFlag{
+			Name:        "poor-speed",
+			Value:       poorSpeed,
+			Usage:       "Speeds below this rate are shown in red, speeds in between in yellow",
+			Destination: &poorSpeed,
+		},
+		&cli.StringFlag{
+			Name:        "provider",
+			Aliases:     []string{"p"},
+			Value:       providerName,
+			Usage:       "Speed test backend to use: fast or cloudflare",
+			Destination: &providerName,
+		},
+		&cli.StringSliceFlag{
+			Name:        "url",
+			Usage:       "Test against the given URL instead of fast.com, e.g. a fast-cli serve-target (repeatable)",
+			Destination: &customURLs,
+		},
+	}
+	if withUpload {
+		flags = append(flags, &cli.BoolFlag{
+			Name:        "upload",
+			Aliases:     []string{"u"},
+			Usage:       "Test upload speed as well",
+			Value:       checkUpload,
+			Destination: &checkUpload,
+		}, &cli.BoolFlag{
+			Name:        "upload-only",
+			Usage:       "Test upload speed only and skip the download test, same as the upload command",
+			Value:       uploadOnly,
+			Destination: &uploadOnly,
+		})
 	}
+	return flags
 }
 
-func run(c *cli.Context) error {
-	initApputils()
+func initApputils() error {
+	if jsonOutput && outputFormat != formatText && outputFormat != formatJSON {
+		return withCodef(codeInvalidArgument, "--json cannot be combined with --format %s", outputFormat)
+	}
+	if jsonOutput {
+		outputFormat = formatJSON
+	}
+	if !slices.Contains(outputFormats, outputFormat) {
+		return withCodef(codeInvalidArgument, "unknown format %q, expected one of %s", outputFormat, strings.Join(outputFormats, ", "))
+	}
+	jsonOutput = outputFormat == formatJSON || outputFormat == formatOoklaJSON
+	if quietOutput && outputFormat != formatText {
+		return withCodef(codeInvalidArgument, "--quiet cannot be combined with --format %s", outputFormat)
+	}
+	utils.AppConfig.JsonOutput = jsonOutput
+	utils.AppConfig.Quiet = quietOutput || outputFormat == formatNagios
+	utils.AppConfig.ASCII = !utils.UnicodeConsole()
+	utils.AppConfig.DecimalComma = !jsonOutput && utils.DecimalComma(locale)
+	utils.AppConfig.Color = !noColor && utils.IsTerminal(os.Stdout) && !utils.IsDumbTerminal() && !utils.NoColorRequested() &&
+		utils.EnableVirtualTerminal(os.Stdout)
 
-	fast.UseHTTPS = !notHTTPS
-	urls, err := fast.GetUrls(4)
-	if err != nil {
-		utils.Errorf("Error getting urls from fast.com service: %v\n", err)
-		return err
+	if maxDuration <= 0 {
+		return withCodef(codeInvalidArgument, "--max-duration must be greater than 0")
+	}
+	if intervalOutput < 0 {
+		return withCodef(codeInvalidArgument, "--interval-output must not be negative")
+	}
+	if intervalOutput > 0 {
+		simpleProgress = true
+		tuiMode = false
 	}
 
-	utils.Debugf("Got %d urls from fast.com service\n", len(urls))
+	// Dynamic progress rewrites the line in place, which only works on a capable terminal.
+	// Older Windows consoles need escape sequence processing switched on first.
+	if !utils.IsTerminal(os.Stderr) || utils.IsDumbTerminal() || !utils.EnableVirtualTerminal(os.Stderr) {
+		simpleProgress = true
+		tuiMode = false
+	}
+	if jsonOutput || quietOutput || outputFormat == formatNagios {
+		simpleProgress = simpleProgress || quietOutput || outputFormat == formatNagios
+		tuiMode = false
+	}
 
-	if len(urls) == 0 {
-		utils.Println("Using fallback endpoint")
-		urls = append(urls, fast.GetDefaultURL())
+	level, err := utils.ParseLogLevel(logLevel)
+	if err != nil {
+		return withCode(codeInvalidArgument, err)
+	}
+	switch {
+	case debugOutput || verbosity >= 2:
+		level = slog.LevelDebug
+		http.DefaultTransport = &utils.LoggingTransport{Base: http.DefaultTransport}
+	case verbosity == 1:
+		level = min(level, slog.LevelInfo)
 	}
 
-	downloadSpeed, err := measureDownloadSpeed(urls)
+	logCloser, err = utils.ConfigureLogging(utils.LogOptions{
+		Level:      level,
+		File:       logFile,
+		MaxSize:    10 * 1024 * 1024,
+		MaxBackups: 3,
+	})
 	if err != nil {
-		utils.Fprintf(os.Stderr, "Error measuring download speed: %v\n", err)
-		return err
+		return withCode(codeInvalidArgument, err)
 	}
 
-	var uploadSpeed Speed
-	if checkUpload {
-		uploadSpeed, err = measureUploadSpeed(urls)
-		if err != nil {
-			utils.Fprintf(os.Stderr, "Error measuring upload speed: %v\n", err)
-			return err
+	if requestHeaders, err = parseHeaders(customHeaders); err != nil {
+		return withCode(codeInvalidArgument, err)
+	}
+	if expectedDownload != "" {
+		if expectedDownloadRate, err = utils.ParseBitRate(expectedDownload); err != nil {
+			return withCode(codeInvalidArgument, err)
 		}
 	}
+	if expectedUpload != "" {
+		if expectedUploadRate, err = utils.ParseBitRate(expectedUpload); err != nil {
+			return withCode(codeInvalidArgument, err)
+		}
+	}
+	if minPercent > 0 && expectedDownload == "" && expectedUpload == "" {
+		return withCodef(codeInvalidArgument, "--min-percent needs --expected or --expected-upload")
+	}
+	if uploadOnly && (singleStream || sweep != "") {
+		return withCodef(codeInvalidArgument, "--upload-only cannot be combined with --single or --sweep, they measure the download speed")
+	}
+	if sweep != "" {
+		if sweepLevels, err = parseSweep(sweep); err != nil {
+			return withCode(codeInvalidArgument, err)
+		}
+	}
+	if (warningRate != "" || criticalRate != "") && outputFormat != formatNagios {
+		return withCodef(codeInvalidArgument, "--warning and --critical need --format nagios")
+	}
+	if outputFormat == formatNagios && sweep != "" {
+		return withCodef(codeInvalidArgument, "--sweep cannot be combined with --format nagios")
+	}
+	if outputFormat == formatOoklaJSON && sweep != "" {
+		return withCodef(codeInvalidArgument, "--sweep cannot be combined with --format ookla-json")
+	}
+	if warningThresholds, err = parseNagiosThresholds(warningRate); err != nil {
+		return withCode(codeInvalidArgument, err)
+	}
+	if criticalThresholds, err = parseNagiosThresholds(criticalRate); err != nil {
+		return withCode(codeInvalidArgument, err)
+	}
+	if limit != "" {
+		if limitRate, err = utils.ParseBitRate(limit); err != nil {
+			return withCode(codeInvalidArgument, err)
+		}
+	}
+	if thresholds.Good, err = utils.ParseBitRate(goodSpeed); err != nil {
+		return withCode(codeInvalidArgument, err)
+	}
+	if thresholds.Poor, err = utils.ParseBitRate(poorSpeed); err != nil {
+		return withCode(codeInvalidArgument, err)
+	}
 
-	printFinalSpeeds(&downloadSpeed, &uploadSpeed, checkUpload)
-
+	slog.Debug("logging configured", "level", level, "file", logFile)
+	slog.Debug("connection settings", "https", !notHTTPS)
 	return nil
 }
 
-func toJSON(v interface{}) string {
-	bytes, err := json.Marshal(v)
-	if err != nil {
-		return ""
+// runTest measures download and, with --upload, upload speed, or only upload speed with --upload-only
+func runTest(c *cli.Context) error {
+	if uploadOnly {
+		return runSpeedTest(c.Context, false, true)
 	}
-	return string(bytes)
+	return runSpeedTest(c.Context, true, checkUpload)
 }
 
-func printFinalSpeeds(downloadSpeed *Speed, uploadSpeed *Speed, checkUpload bool) {
-	if jsonOutput {
-		results := SpeedResults{
-			Download: *downloadSpeed,
+// runDownload measures download speed only
+func runDownload(c *cli.Context) error {
+	return runSpeedTest(c.Context, true, false)
+}
+
+// runUpload measures upload speed only
+func runUpload(c *cli.Context) error {
+	return runSpeedTest(c.Context, false, true)
+}
+
+// runLatency measures the unloaded latency only
+func runLatency(c *cli.Context) error {
+	return runSpeedTest(c.Context, false, false)
+}
+
+// runSpeedTest measures the latency followed by the selected directions and prints the results
+func runSpeedTest(ctx context.Context, download bool, upload bool) error {
+	if err := initApputils(); err != nil {
+		return err
+	}
+	if appendFile != "" {
+		if _, err := appendFormat(appendFile); err != nil {
+			return withCode(codeInvalidArgument, err)
 		}
-		if checkUpload {
-			results.Upload = uploadSpeed
+	}
+	if shareFile != "" {
+		if _, err := card.Format(shareFile); err != nil {
+			return withCode(codeInvalidArgument, err)
 		}
-		utils.PrintJSON("%s\n", toJSON(results))
-	} else {
-		speedsText := "speed"
-		if checkUpload {
-			speedsText = "speeds"
+	}
+	started := time.Now()
+
+	var progressEvents *sse.Broker
+	if progressListen != "" {
+		broker, stop, err := serveProgress(progressListen)
+		if err != nil {
+			return withCodef(codeInvalidArgument, "could not listen for progress stream clients: %w", err)
 		}
-		utils.Printf(fmt.Sprintf("\n🚀 Final estimated %s:\n", speedsText))
-		utils.Printf("   Download: %.2f %s\n", downloadSpeed.Speed, downloadSpeed.Unit)
-		if checkUpload && uploadSpeed != nil {
-			utils.Printf("   Upload:    %.2f %s\n", uploadSpeed.Speed, uploadSpeed.Unit)
+		defer stop()
+		progressEvents = broker
+	}
+
+	if mockMode {
+		srv := mock.NewServer(mock.DefaultRate)
+		defer srv.Close()
+		mockURL = srv.URL
+	}
+	testProvider, err := newProvider()
+	if err != nil {
+		return err
+	}
+	if upload && !testProvider.SupportsUpload() {
+		return withCodef(codeUploadUnsupported, "the %s provider does not support upload tests", testProvider.Name())
+	}
+	if preflight {
+		utils.Statusf("Running pre-flight checks...\n")
+		if err := checkEndpoint(ctx, apiURL(testProvider), utils.Statusf); err != nil {
+			return err
 		}
 	}
-}
+	candidates, err := getTargets(ctx, testProvider)
+	if err != nil {
+		return err
+	}
+	// The latency probes pick the servers, the closest one is reported as the unloaded latency
+	targets, latency, latencyErr := selectTargets(ctx, candidates, testServers, worstServers)
+	for _, target := range targets {
+		slog.Info("test server", "url", target.URL, "location", target.Location)
+	}
 
-func measureDownloadSpeed(urls []string) (Speed, error) {
-	client := &http.Client{}
-	count := uint64(len(urls))
-	primaryBandwidthMeter := utils.BandwidthMeter{}
-	completed := make(chan bool)
+	pool := newTargetPool(testProvider, targets)
+	client := newTestClient()
+	if preflight {
+		if err := probeTarget(ctx, client, testProvider, targets[0], utils.Statusf); err != nil {
+			return err
+		}
+	}
 
-	primaryBandwidthMeter.Start()
-	if !simpleProgress {
-		utils.Println("⬇️ Estimating download speed...")
+	display = newProgressDisplay()
+	if progressEvents != nil {
+		display = &eventDisplay{progressDisplay: display, broker: progressEvents}
 	}
+	defer display.close()
 
-	for _, url := range urls {
-		go func(url string) {
-			defer func() { completed <- true }() // Ensure completion signal
+	if latencyErr != nil {
+		if !download && !upload {
+			return withCodef(failureCode(codeLatencyFailed, latencyErr), "error measuring latency: %w", latencyErr)
+		}
+		slog.Warn("could not measure latency", "error", latencyErr)
+	}
+	display.showLatency(latency)
 
-			request, err := http.NewRequest("GET", url, nil)
-			if err != nil {
-				utils.Errorln("Failed to create request", "error", err)
-				return
-			}
-			request.Header.Set("User-Agent", displayVersion)
+	results := SpeedResults{
+		LatencyMs: milliseconds(latency),
+		Metadata:  testProvider.Metadata(),
+		timestamp: started,
+	}
 
-			response, err := client.Do(request)
+	switch {
+	case download && len(sweepLevels) > 0:
+		sweep, err := measureSweep(ctx, client, pool, sweepLevels)
+		if err != nil {
+			return withCodef(failureCode(codeDownloadFailed, err), "error measuring download speed: %w", err)
+		}
+		results.Sweep = sweep
+	case download:
+		downloadSpeed, err := measureDownloadSpeed(ctx, client, pool, "download", pool.len(), true)
+		if err != nil {
+			return withCodef(failureCode(codeDownloadFailed, err), "error measuring download speed: %w", err)
+		}
+		if singleStream {
+			singleSpeed, err := measureDownloadSpeed(ctx, client, pool, "single connection download", 1, false)
 			if err != nil {
-				utils.Errorln("Failed to perform request", "error", err)
-				return
+				return withCodef(failureCode(codeDownloadFailed, err), "error measuring single connection download speed: %w", err)
 			}
-			defer response.Body.Close()
+			results.SingleDownload = &singleSpeed
+		}
+		verdict := streamingVerdict(downloadSpeed.bitsPerSec, latency)
+		results.Download = &downloadSpeed
+		results.Streaming = &verdict
+	}
 
-			tapMeter := io.TeeReader(response.Body, &primaryBandwidthMeter)
-			_, err = io.Copy(io.Discard, tapMeter)
-			if err != nil {
-				utils.Errorln("Failed to copy response body", "error", err)
-				return
-			}
-		}(url)
+	if upload {
+		uploadSpeed, err := measureUploadSpeed(ctx, client, pool)
+		if err != nil {
+			return withCodef(failureCode(codeUploadFailed, err), "error measuring upload speed: %w", err)
+		}
+		results.Upload = &uploadSpeed
+	}
+	// The measurements stop early when interrupted, their results would be too low
+	if ctx.Err() != nil {
+		return withCodef(codeAborted, "test aborted")
 	}
 
-	monitorProgress(&primaryBandwidthMeter, maxDuration, completed, count)
+	compareToExpected(results.Download, expectedDownloadRate)
+	compareToExpected(results.Upload, expectedUploadRate)
 
-	speed, unit := utils.BitsPerSecWithUnit(primaryBandwidthMeter.Bandwidth())
-	return Speed{Speed: speed, Unit: unit}, nil
+	display.close()
+	// The pool may have swapped in other servers when urls expired
+	tested := pool.snapshot()
+	results.Servers = describeServers(ctx, tested)
+	// Tracing after the measurements keeps the probes out of the results
+	if traceRoute {
+		results.Traces = traceTargets(ctx, tested)
+	}
+	if !simpleProgress && !tuiMode && (download || upload) {
+		utils.Statusln()
+	}
+	writeResults(os.Stdout, results)
+	resultsPrinted = true
+	if progressEvents != nil {
+		progressEvents.Publish("result", results)
+	}
+	if results.Download != nil {
+		printStreamErrors("download", results.Download.Errors)
+	}
+	if results.SingleDownload != nil {
+		printStreamErrors("single connection download", results.SingleDownload.Errors)
+	}
+	if results.Upload != nil {
+		printStreamErrors("upload", results.Upload.Errors)
+	}
+	if outputFile != "" {
+		if err := writeOutputFile(outputFile, results); err != nil {
+			return withCodef(codeOutputFailed, "error writing results: %w", err)
+		}
+	}
+	if appendFile != "" {
+		if err := appendResults(appendFile, results, started); err != nil {
+			return withCodef(codeOutputFailed, "error appending results: %w", err)
+		}
+	}
+	if shareFile != "" {
+		if err := card.Write(shareFile, resultCard(results, started)); err != nil {
+			return withCodef(codeOutputFailed, "error writing result card: %w", err)
+		}
+	}
+	if notifyDone {
+		sendNotification(results)
+	}
+
+	if outputFormat == formatNagios {
+		if err := newNagiosCheck(results).err(); err != nil {
+			return err
+		}
+	}
+	return checkExpected(results)
+}
+
+// compareToExpected records how much of the expected rate, in bits per second, the speed reached
+func compareToExpected(speed *Speed, expected float64) {
+	if speed == nil || expected <= 0 {
+		return
+	}
+	percent := math.Round(speed.bitsPerSec/expected*1000) / 10
+	speed.PercentOfExpected = &percent
 }
 
-func measureUploadSpeed(urls []string) (Speed, error) {
-	client := &http.Client{}
-	uploadData := make([]byte, 26214400) // 25 MB
-	chunkSize := 1024 * 1024             // 1 MB chunk
-	count := uint64(len(urls))
+// checkExpected returns an error if a speed fell below --min-percent of the expected speed
+func checkExpected(results SpeedResults) error {
+	for _, result := range []struct {
+		direction string
+		speed     *Speed
+		expected  string
+	}{
+		{"download", results.Download, expectedDownload},
+		{"upload", results.Upload, expectedUpload},
+	} {
+		if minPercent <= 0 || result.speed == nil || result.speed.PercentOfExpected == nil {
+			continue
+		}
+		if percent := *result.speed.PercentOfExpected; percent < minPercent {
+			return withCodef(codeBelowExpected, "%s speed reached %.1f%% of the expected %s, below the minimum of %.0f%%",
+				result.direction, percent, result.expected, minPercent)
+		}
+	}
+	return nil
+}
 
-	primaryBandwidthMeter := utils.BandwidthMeter{}
-	completed := make(chan bool)
+func newProvider() (provider.Provider, error) {
+	if mockURL != "" {
+		if len(customURLs.Value()) > 0 || providerName != "fast" {
+			return nil, withCodef(codeInvalidArgument, "--mock cannot be combined with --url or another provider")
+		}
+		p := fast.NewProvider(false)
+		p.BaseURL = mockURL
+		p.TokenCachePath = ""
+		return p, nil
+	}
+	if urls := customURLs.Value(); len(urls) > 0 {
+		slog.Debug("using custom urls", "count", len(urls))
+		return provider.NewStatic(urls), nil
+	}
 
-	primaryBandwidthMeter.Start()
-	if !simpleProgress {
-		utils.Println("\n⬆️ Estimating upload speed...")
+	switch providerName {
+	case "fast":
+		return fast.NewProvider(!notHTTPS), nil
+	case "cloudflare":
+		return cloudflare.NewProvider(), nil
+	default:
+		return nil, withCodef(codeInvalidArgument, "unknown provider %q, expected fast or cloudflare", providerName)
 	}
-	for _, url := range urls {
-		go func(url string) {
-			defer func() { completed <- true }() // Ensure completion signal
+}
 
-			for offset := 0; offset < len(uploadData); offset += chunkSize {
-				tapMeter := bytes.NewReader(uploadData[offset:min(offset+chunkSize, len(uploadData))])
+func getTargets(ctx context.Context, p provider.Provider) ([]provider.Target, error) {
+	// Bound the whole lookup, a blocked api can otherwise keep every retry waiting
+	start := time.Now()
+	lookupCtx, cancel := context.WithTimeout(ctx, startupTimeout)
+	defer cancel()
 
-				request, err := http.NewRequest("POST", url, tapMeter)
-				if err != nil {
-					utils.Errorln("Failed to create request", "error", err)
-					return
-				}
-				request.Header.Set("User-Agent", displayVersion)
-				request.Header.Set("Content-Type", "application/octet-stream")
-				request.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d",
-					offset, min(offset+chunkSize-1, len(uploadData)-1), len(uploadData)))
-
-				tapReadMeter := io.TeeReader(tapMeter, &primaryBandwidthMeter)
-				buffer := &bytes.Buffer{}
-				_, err = io.Copy(buffer, tapReadMeter)
-				if err != nil {
-					utils.Errorln("Failed to copy request body", "error", err)
-					return
-				}
-				request.Body = io.NopCloser(buffer)
-				resp, err := client.Do(request)
-				if err != nil {
-					utils.Errorln("Failed to perform request", "error", err)
-					return
-				}
-				resp.Body.Close()
-				if err != nil {
-					utils.Errorln("Failed to close response body", "error", err)
-					return
-				}
-			}
-		}(url)
+	targets, err := p.GetTargets(lookupCtx, candidateServers)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return nil, withCodef(codeProviderUnavailable, "timed out after %s getting urls from %s service%s", startupTimeout, p.Name(), providerHint(p))
+	}
+	if err != nil {
+		return nil, withCodef(failureCode(codeProviderUnavailable, err), "error getting urls from %s service: %w%s", p.Name(), err, providerHint(p))
 	}
 
-	monitorProgress(&primaryBandwidthMeter, maxDuration, completed, count)
+	slog.Info("got test urls", "count", len(targets), "provider", p.Name(), "duration", time.Since(start))
+	return targets, nil
+}
 
-	speed, unit := utils.BitsPerSecWithUnit(primaryBandwidthMeter.Bandwidth())
-	return Speed{Speed: speed, Unit: unit}, nil
+// providerHint suggests alternatives for when the default provider is blocked on the network
+func providerHint(p provider.Provider) string {
+	if p.Name() != "fast" {
+		return ""
+	}
+	return "; if fast.com is blocked on this network, try --provider cloudflare or --url with your own fast-cli serve-target"
 }
-func monitorProgress(bandwidthMeter *utils.BandwidthMeter, maxDuration time.Duration, completed chan bool, total uint64) {
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
 
-	timeout := time.After(maxDuration)
-	start := time.Now()
-	var completeCount uint64
+func serveTarget(c *cli.Context) error {
+	if err := initApputils(); err != nil {
+		return err
+	}
 
-	for {
-		select {
-		case <-timeout:
-			if !simpleProgress {
-				printProgress(bandwidthMeter, start, maxDuration)
-			}
-			return
+	utils.Statusf("Serving speed test target on %s, test against it with --url http://<host>:<port>%s\n", listenAddr, server.Path)
+	return server.ListenAndServe(c.Context, listenAddr)
+}
 
-		case <-ticker.C:
-			if !simpleProgress {
-				printProgress(bandwidthMeter, start, maxDuration)
-			}
+func toJSON(v interface{}) string {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(bytes)
+}
 
-		case <-completed:
-			completeCount++
-			if completeCount == total {
-				printProgress(bandwidthMeter, start, maxDuration, true)
-				return
-			}
+// writeResults writes the final results in the chosen output format
+func writeResults(w io.Writer, results SpeedResults) {
+	switch outputFormat {
+	case formatJSON:
+		utils.FprintJSON(w, "%s\n", toJSON(results))
+		return
+	case formatOoklaJSON:
+		utils.FprintJSON(w, "%s\n", toJSON(ooklaResults(results)))
+		return
+	case formatNagios:
+		writeNagios(w, results)
+		return
+	}
+	if quietOutput {
+		var values []string
+		if results.Download != nil {
+			values = append(values, megabitsPerSec(results.Download))
+		}
+		for _, level := range results.Sweep {
+			values = append(values, megabitsPerSec(&level.Speed))
+		}
+		if results.Upload != nil {
+			values = append(values, megabitsPerSec(results.Upload))
+		}
+		if len(values) == 0 {
+			values = append(values, strconv.FormatFloat(results.LatencyMs, 'f', 1, 64))
+		}
+		utils.Fprintf(w, "%s\n", strings.Join(values, " "))
+		return
+	}
+
+	heading := "speed"
+	switch {
+	case results.Download != nil && results.Upload != nil, len(results.Sweep) > 0:
+		heading = "speeds"
+	case results.Download == nil && results.Upload == nil:
+		heading = "latency"
+	}
+	utils.Fprintf(w, "%sFinal estimated %s:\n", utils.Symbol("🚀 ", ""), heading)
+	if results.Download != nil {
+		utils.Fprintf(w, "   Download: %s\n", formatSpeed(results.Download))
+		printSparkline(w, results.Download, "             ")
+	}
+	if results.SingleDownload != nil {
+		utils.Fprintf(w, "   Single connection: %s\n", formatSpeed(results.SingleDownload))
+	}
+	if len(results.Sweep) > 0 {
+		printSweep(w, results.Sweep)
+	}
+	if results.Upload != nil {
+		utils.Fprintf(w, "   Upload:    %s\n", formatSpeed(results.Upload))
+		printSparkline(w, results.Upload, "              ")
+	}
+	if results.LatencyMs > 0 {
+		utils.Fprintf(w, "   Latency:  %s ms\n", utils.Decimal(results.LatencyMs, 1))
+	}
+	if results.Streaming != nil {
+		utils.Fprintf(w, "   Streaming: %s\n", results.Streaming.Summary)
+	}
+	if metadata := results.Metadata; metadata.Server != "" {
+		utils.Fprintf(w, "   Server:   %s (%s)\n", metadata.Server, metadata.Provider)
+	}
+	if len(results.Servers) > 0 {
+		var servers []string
+		for _, server := range results.Servers {
+			servers = append(servers, server.String())
+		}
+		utils.Fprintf(w, "   Servers:  %s\n", strings.Join(servers, ", "))
+	}
+	if metadata := results.Metadata; metadata.ClientIP != "" {
+		utils.Fprintf(w, "   Client:   %s %s\n", metadata.ClientIP, metadata.ISP)
+	}
+	for _, trace := range results.Traces {
+		utils.Fprintf(w, "   Route to %s:\n", trace.Host)
+		for _, hop := range trace.Hops {
+			utils.Fprintf(w, "     %s\n", hop)
+		}
+		if trace.Error != "" {
+			utils.Fprintf(w, "     %s\n", trace.Error)
 		}
 	}
 }
 
-func printProgress(bandwidthMeter *utils.BandwidthMeter, start time.Time, maxDuration time.Duration, forceComplete ...bool) {
-	if !simpleProgress {
-		spinner := spinnerStates[spinnerIndex]
-		spinnerIndex = (spinnerIndex + 1) % len(spinnerStates)
+// writeOutputFile writes the final results to path, without colors
+func writeOutputFile(path string, results SpeedResults) error {
+	color := utils.AppConfig.Color
+	utils.AppConfig.Color = false
+	defer func() { utils.AppConfig.Color = color }()
 
-		elapsed := time.Since(start)
-		var percentComplete float64
+	var buf bytes.Buffer
+	writeResults(&buf, results)
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
 
-		// If forceComplete is provided and true, set percentComplete to 100
-		if len(forceComplete) > 0 && forceComplete[0] {
-			percentComplete = 100
-		} else {
-			percentComplete = (elapsed.Seconds() / maxDuration.Seconds()) * 100
-		}
+// sendNotification shows the result as a desktop notification, failures only produce a warning
+func sendNotification(results SpeedResults) {
+	var lines []string
+	if results.Download != nil {
+		lines = append(lines, fmt.Sprintf("Download: %s %s", utils.Decimal(results.Download.Speed, 2), results.Download.Unit))
+	}
+	if results.Upload != nil {
+		lines = append(lines, fmt.Sprintf("Upload: %s %s", utils.Decimal(results.Upload.Speed, 2), results.Upload.Unit))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, fmt.Sprintf("Latency: %s ms", utils.Decimal(results.LatencyMs, 1)))
+	}
+	if err := notify.Send("fast-cli speed test finished", strings.Join(lines, "\n")); err != nil {
+		slog.Warn("could not send desktop notification", "error", err)
+	}
+}
+
+// resultCard summarizes the results for a shareable image
+func resultCard(results SpeedResults, timestamp time.Time) card.Card {
+	c := card.Card{Title: "fast-cli speed test"}
+	if results.Download != nil {
+		c.Rows = append(c.Rows, card.Row{Label: "Download", Value: utils.Decimal(results.Download.Speed, 2) + " " + results.Download.Unit})
+	}
+	if results.Upload != nil {
+		c.Rows = append(c.Rows, card.Row{Label: "Upload", Value: utils.Decimal(results.Upload.Speed, 2) + " " + results.Upload.Unit})
+	}
+	c.Rows = append(c.Rows, card.Row{Label: "Latency", Value: utils.Decimal(results.LatencyMs, 1) + " ms"})
 
-		utils.Printf("\r%s %s - %.2f%% completed",
-			spinner,
-			utils.BitsPerSec(bandwidthMeter.Bandwidth()),
-			percentComplete)
+	if isp := strings.Trim(fmt.Sprintf("%s, %s", results.Metadata.ISP, results.Metadata.Location), ", "); isp != "" {
+		c.Footer = append(c.Footer, isp)
 	}
+	c.Footer = append(c.Footer, fmt.Sprintf("%s via %s", timestamp.Format("2006-01-02 15:04 MST"), results.Metadata.Provider))
+	return c
 }
-func min(a, b int) int {
-	if a < b {
-		return a
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// megabitsPerSec formats a speed in Mbps, the fixed unit of machine readable output
+func megabitsPerSec(speed *Speed) string {
+	if speed == nil {
+		return ""
+	}
+	return strconv.FormatFloat(speed.bitsPerSec/1e6, 'f', 2, 64)
+}
+
+// formatSpeed formats a speed for text output, colored by the speed thresholds
+func formatSpeed(speed *Speed) string {
+	text := thresholds.ColorizeSpeed(speed.bitsPerSec, utils.Decimal(speed.Speed, 2)+" "+speed.Unit)
+	var details []string
+	if speed.Consistency != nil {
+		details = append(details, fmt.Sprintf("%.0f%% consistent", *speed.Consistency))
+	}
+	if speed.PercentOfExpected != nil {
+		details = append(details, fmt.Sprintf("%.0f%% of plan", *speed.PercentOfExpected))
+	}
+	if len(details) > 0 {
+		text += fmt.Sprintf("  (%s)", strings.Join(details, ", "))
+	}
+	return text
+}
+
+// printSparkline shows how the throughput developed over the test below its headline number
+func printSparkline(w io.Writer, speed *Speed, indent string) {
+	if len(speed.samples) < 2 {
+		return
+	}
+	utils.Fprintf(w, "%s%s\n", indent, utils.Sparkline(speed.samples, sparklineWidth))
+}
+
+func printStreamErrors(direction string, errs []string) {
+	if len(errs) == 0 {
+		return
+	}
+	utils.Fprintf(os.Stderr, "\n%s%d %s streams failed, the result may be too low:\n", utils.Symbol("⚠️ ", "Warning: "), len(errs), direction)
+	for _, err := range errs {
+		utils.Fprintf(os.Stderr, "   - %s\n", err)
 	}
-	return b
 }
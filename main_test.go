@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"mikkelam/fast-cli/provider"
+	"mikkelam/fast-cli/utils"
+)
+
+// testResults returns results as a run against the mock server could produce them
+func testResults() SpeedResults {
+	consistency := 94.0
+	return SpeedResults{
+		Download: &Speed{
+			Speed:       102.5,
+			Unit:        "Mbps",
+			Consistency: &consistency,
+			bitsPerSec:  102.5e6,
+			bytes:       51250000,
+			elapsed:     4 * time.Second,
+		},
+		LatencyMs: 9.1,
+		Metadata:  provider.Metadata{Provider: "fast", ClientIP: "203.0.113.7", ISP: "Example Broadband"},
+		timestamp: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// writeResultsAs renders results in the given output format
+func writeResultsAs(t *testing.T, format string, results SpeedResults) string {
+	t.Helper()
+	previous, previousJSON := outputFormat, utils.AppConfig.JsonOutput
+	t.Cleanup(func() {
+		outputFormat, utils.AppConfig.JsonOutput = previous, previousJSON
+	})
+	outputFormat = format
+	utils.AppConfig.JsonOutput = format == formatJSON || format == formatOoklaJSON
+
+	var buf bytes.Buffer
+	writeResults(&buf, results)
+	return buf.String()
+}
+
+func TestWriteResultsText(t *testing.T) {
+	out := writeResultsAs(t, formatText, testResults())
+	for _, want := range []string{"Final estimated speed:", "Download: 102.50 Mbps  (94% consistent)", "Latency:  9.1 ms"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("text output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteResultsJSON(t *testing.T) {
+	var decoded struct {
+		Download struct {
+			Speed       float64 `json:"speed"`
+			Unit        string  `json:"unit"`
+			Consistency float64 `json:"consistency"`
+		} `json:"download"`
+		Upload    *Speed  `json:"upload"`
+		LatencyMs float64 `json:"latency_ms"`
+		Metadata  struct {
+			ISP string `json:"isp"`
+		} `json:"metadata"`
+	}
+	out := writeResultsAs(t, formatJSON, testResults())
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if decoded.Download.Speed != 102.5 || decoded.Download.Unit != "Mbps" || decoded.Download.Consistency != 94 {
+		t.Errorf("download = %+v, want 102.5 Mbps, 94%% consistent", decoded.Download)
+	}
+	if decoded.Upload != nil || decoded.LatencyMs != 9.1 || decoded.Metadata.ISP != "Example Broadband" {
+		t.Errorf("unexpected JSON output %s", out)
+	}
+}
+
+func TestWriteResultsOoklaJSON(t *testing.T) {
+	var decoded ooklaResult
+	out := writeResultsAs(t, formatOoklaJSON, testResults())
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	want := ooklaTransfer{Bandwidth: 12812500, Bytes: 51250000, Elapsed: 4000}
+	if decoded.Type != "result" || decoded.Download == nil || *decoded.Download != want {
+		t.Errorf("download = %+v, want %+v", decoded.Download, want)
+	}
+	if decoded.Upload != nil || decoded.Ping.Latency != 9.1 || decoded.Interface.ExternalIP != "203.0.113.7" {
+		t.Errorf("unexpected Ookla JSON output %s", out)
+	}
+}
+
+func TestWriteResultsNagios(t *testing.T) {
+	t.Cleanup(func() {
+		warningThresholds, criticalThresholds = nagiosThresholds{}, nagiosThresholds{}
+	})
+	tests := []struct {
+		warning  nagiosThresholds
+		critical nagiosThresholds
+		want     string
+	}{
+		{want: "OK - download 102.5Mbps | download=102500000;;;0; latency=9.1ms;;;0;\n"},
+		{
+			warning:  nagiosThresholds{download: 200e6},
+			critical: nagiosThresholds{download: 50e6},
+			want:     "WARNING - download 102.5Mbps (download below 200Mbps) | download=102500000;200000000:;50000000:;0; latency=9.1ms;;;0;\n",
+		},
+		{
+			warning:  nagiosThresholds{download: 500e6},
+			critical: nagiosThresholds{download: 200e6},
+			want:     "CRITICAL - download 102.5Mbps (download below 200Mbps) | download=102500000;500000000:;200000000:;0; latency=9.1ms;;;0;\n",
+		},
+	}
+	for _, tt := range tests {
+		warningThresholds, criticalThresholds = tt.warning, tt.critical
+		if out := writeResultsAs(t, formatNagios, testResults()); out != tt.want {
+			t.Errorf("nagios output = %q, want %q", out, tt.want)
+		}
+	}
+}
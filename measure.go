@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"mikkelam/fast-cli/utils"
+)
+
+// copyBufferSize is large enough to keep the copy loop cheap on multi-gigabit links
+const copyBufferSize = 256 * 1024
+
+// copyBuffers holds the buffers download bodies are copied through
+var copyBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, copyBufferSize)
+		return &buf
+	},
+}
+
+// Download requests are sized to take about requestDuration, within these bounds
+const (
+	initialRequestSize = 26214400 // 25 MB, the size fast.com starts with
+	minRequestSize     = 1024 * 1024
+	maxRequestSize     = 256 * 1024 * 1024
+	requestDuration    = 2 * time.Second
+)
+
+// minResponseSize is the smallest download response that counts as test data,
+// an empty or tiny body would otherwise be requested again in a tight loop
+const minResponseSize = 64 * 1024
+
+// newTestClient returns the client shared by all test streams
+func newTestClient() *http.Client {
+	opts := utils.DefaultTransportOptions()
+	opts.DisableKeepAlives = noKeepAlive
+	opts.DialTimeout = requestTimeout
+	opts.TLSHandshakeTimeout = requestTimeout
+	return &http.Client{Transport: &utils.LoggingTransport{Base: utils.NewTransport(opts)}}
+}
+
+// measureDownloadSpeed measures the download speed over up to streams
+// connections, or exactly streams connections without ramp. direction names
+// the measurement in the output.
+func measureDownloadSpeed(ctx context.Context, client *http.Client, pool *targetPool, direction string, streams int, ramp bool) (Speed, error) {
+	count := streams
+	m := newMeasurement(direction, count)
+	m.ramp = ramp
+	completed := make(chan streamResult, count)
+
+	// Streams still running once the test is over are stopped, so they do not
+	// skew the measurements that follow
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// A restarted stream moves on to the next server
+	errs := monitorProgress(ctx, m, maxDuration, completed, func(stream int, attempt int) {
+		go func() {
+			err := downloadStream(ctx, client, pool, stream+attempt, m.streamWriter(stream))
+			completed <- streamResult{stream, err}
+		}()
+	})
+	slog.Info("download finished", "direction", direction, "duration", m.meter.Duration(),
+		"bytes", m.meter.BytesRead(), "streams", m.started, "failed_streams", len(errs))
+	if err := ctx.Err(); err != nil {
+		return Speed{}, err
+	}
+	if err := streamsFailed(direction, errs, m.started); err != nil {
+		return Speed{}, err
+	}
+	if err := nothingTransferred(m); err != nil {
+		return Speed{}, err
+	}
+
+	return newSpeed(m, errs), nil
+}
+
+func measureUploadSpeed(ctx context.Context, client *http.Client, pool *targetPool) (Speed, error) {
+	uploadData := make([]byte, 26214400) // 25 MB
+	count := pool.len()
+	m := newMeasurement("upload", count)
+	completed := make(chan streamResult, count)
+
+	// Streams still running once the test is over are stopped, so they do not
+	// skew the measurements that follow
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// A restarted stream moves on to the next server
+	errs := monitorProgress(ctx, m, maxDuration, completed, func(stream int, attempt int) {
+		go func() {
+			err := uploadStream(ctx, client, pool, stream+attempt, uploadData, m.streamWriter(stream))
+			completed <- streamResult{stream, err}
+		}()
+	})
+	slog.Info("upload finished", "duration", m.meter.Duration(),
+		"bytes", m.meter.BytesRead(), "streams", m.started, "failed_streams", len(errs))
+	if err := ctx.Err(); err != nil {
+		return Speed{}, err
+	}
+	if err := streamsFailed("upload", errs, m.started); err != nil {
+		return Speed{}, err
+	}
+	if err := nothingTransferred(m); err != nil {
+		return Speed{}, err
+	}
+
+	return newSpeed(m, errs), nil
+}
+
+// newSpeed summarizes a finished measurement
+func newSpeed(m *measurement, errs []error) Speed {
+	bytesPerSec := m.meter.Bandwidth()
+	speed, unit := utils.BitsPerSecWithUnit(bytesPerSec)
+	result := Speed{
+		Speed:      speed,
+		Unit:       unit,
+		Errors:     errorStrings(errs),
+		bitsPerSec: bytesPerSec * 8,
+		samples:    m.samples,
+		bytes:      m.meter.BytesRead(),
+		elapsed:    m.meter.Duration(),
+	}
+	if score, ok := consistency(m.samples); ok {
+		result.Consistency = &score
+	}
+	return result
+}
+
+// consistency scores how steady the throughput was as a percentage, 100 minus
+// the coefficient of variation of the per-second throughput. It needs at least
+// two seconds of samples.
+func consistency(samples []float64) (float64, bool) {
+	perSecond := int(time.Second / sampleInterval)
+	var seconds []float64
+	for i := 0; i+perSecond <= len(samples); i += perSecond {
+		sum := 0.0
+		for _, sample := range samples[i : i+perSecond] {
+			sum += sample
+		}
+		seconds = append(seconds, sum/float64(perSecond))
+	}
+	if len(seconds) < 2 {
+		return 0, false
+	}
+
+	mean := 0.0
+	for _, s := range seconds {
+		mean += s
+	}
+	mean /= float64(len(seconds))
+	if mean == 0 {
+		return 0, false
+	}
+
+	variance := 0.0
+	for _, s := range seconds {
+		variance += (s - mean) * (s - mean)
+	}
+	variance /= float64(len(seconds))
+
+	score := (1 - math.Sqrt(variance)/mean) * 100
+	return math.Round(max(score, 0)*10) / 10, true
+}
+
+// downloadStream downloads from the stream's target until the test ends,
+// refreshing expired targets. Each request is sized to take about
+// requestDuration at the speed the previous request reached.
+func downloadStream(ctx context.Context, client *http.Client, pool *targetPool, stream int, meter io.Writer) error {
+	size := int64(initialRequestSize)
+	for refreshes := 0; ctx.Err() == nil; {
+		target, generation := pool.get(stream)
+		url := pool.provider.DownloadURL(target, size)
+		start := time.Now()
+		n, expired, err := download(ctx, client, url, meter)
+		elapsed := time.Since(start)
+		slog.Info("download request finished", "stream", stream, "url", url,
+			"bytes", n, "duration", elapsed, "error", err)
+		if expired && refreshes < maxRefreshes {
+			if err := pool.refresh(ctx, generation); err != nil {
+				return fmt.Errorf("failed to refresh test urls: %w", err)
+			}
+			refreshes++
+			continue
+		}
+		if ctx.Err() != nil {
+			// The test ended while the request was running
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if n < minResponseSize {
+			return fmt.Errorf("response too short for a speed test: %d bytes from %s", n, url)
+		}
+		refreshes = 0
+		size = nextRequestSize(n, elapsed)
+	}
+	return nil
+}
+
+// nextRequestSize returns the size of a download taking about requestDuration
+// at the speed of a request that transferred n bytes in elapsed
+func nextRequestSize(n int64, elapsed time.Duration) int64 {
+	if elapsed <= 0 {
+		return maxRequestSize
+	}
+	size := int64(float64(n) / elapsed.Seconds() * requestDuration.Seconds())
+	return min(max(size, minRequestSize), maxRequestSize)
+}
+
+// download fetches url into the meter, returning the number of bytes read and
+// whether the url had expired
+func download(ctx context.Context, client *http.Client, url string, meter io.Writer) (n int64, expired bool, err error) {
+	ctx, progress, cancel := withRequestTimeout(ctx)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create request: %w", err)
+	}
+	setRequestHeaders(request)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to perform request: %w", requestError(ctx, err))
+	}
+	defer response.Body.Close()
+
+	if isExpired(response) {
+		return 0, true, fmt.Errorf("test url expired: %s", response.Status)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return 0, false, fmt.Errorf("unexpected response: %s", response.Status)
+	}
+
+	// The body goes straight into the meter, io.Discard would read it in small chunks
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	n, err = io.CopyBuffer(io.MultiWriter(meter, progress), response.Body, *buf)
+	if err != nil {
+		return n, false, fmt.Errorf("failed to copy response body: %w", requestError(ctx, err))
+	}
+	return n, false, nil
+}
+
+// uploadStream uploads data in chunks to the stream's target until the test
+// ends, starting over once all data was sent and refreshing expired targets
+func uploadStream(ctx context.Context, client *http.Client, pool *targetPool, stream int, uploadData []byte, meter io.Writer) error {
+	chunkSize := 1024 * 1024 // 1 MB chunk
+	start := time.Now()
+
+	target, generation := pool.get(stream)
+	refreshes := 0
+	defer func() {
+		slog.Info("upload stream finished", "stream", stream, "url", target.UploadURL, "duration", time.Since(start))
+	}()
+	for offset := 0; ctx.Err() == nil; offset += chunkSize {
+		if offset >= len(uploadData) {
+			offset = 0
+		}
+		chunk := uploadData[offset:min(offset+chunkSize, len(uploadData))]
+
+		// Bytes are counted as the transport reads them, without copying the chunk
+		requestCtx, progress, cancel := withRequestTimeout(ctx)
+		body := io.TeeReader(bytes.NewReader(chunk), io.MultiWriter(meter, progress))
+		request, err := http.NewRequestWithContext(requestCtx, "POST", target.UploadURL, body)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		request.ContentLength = int64(len(chunk))
+		setRequestHeaders(request)
+		request.Header.Set("Content-Type", "application/octet-stream")
+		request.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d",
+			offset, min(offset+chunkSize-1, len(uploadData)-1), len(uploadData)))
+
+		resp, err := client.Do(request)
+		if err != nil {
+			err = requestError(requestCtx, err)
+			cancel()
+			if ctx.Err() != nil {
+				// The test ended while the request was running
+				return nil
+			}
+			return fmt.Errorf("failed to perform request: %w", err)
+		}
+		resp.Body.Close()
+		cancel()
+
+		if isExpired(resp) && refreshes < maxRefreshes {
+			if err := pool.refresh(ctx, generation); err != nil {
+				return fmt.Errorf("failed to refresh test urls: %w", err)
+			}
+			refreshes++
+			target, generation = pool.get(stream)
+			offset -= chunkSize // Retry the chunk against the new target
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected response: %s", resp.Status)
+		}
+		refreshes = 0
+	}
+	return nil
+}
+
+// setRequestHeaders sets the headers common to all test requests
+func setRequestHeaders(request *http.Request) {
+	request.Header.Set("User-Agent", userAgent)
+	for key, values := range requestHeaders {
+		request.Header[key] = values
+	}
+}
+
+// headerFlag collects repeated --header values, unlike a string slice flag it
+// does not split them on commas
+type headerFlag []string
+
+func (h *headerFlag) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
+func (h *headerFlag) String() string {
+	return strings.Join(*h, "; ")
+}
+
+// parseHeaders parses "Key: Value" pairs as given to --header
+func parseHeaders(pairs []string) (http.Header, error) {
+	header := http.Header{}
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, ":")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("invalid header %q, expected \"Key: Value\"", pair)
+		}
+		header.Add(key, strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+// progressWriter calls its function for every write, it reports transfer progress
+type progressWriter func()
+
+func (f progressWriter) Write(p []byte) (int, error) {
+	f()
+	return len(p), nil
+}
+
+// withRequestTimeout returns a context for a single test request that is
+// canceled once the request made no progress for requestTimeout. Writes to the
+// returned progress writer count as progress.
+func withRequestTimeout(parent context.Context) (context.Context, progressWriter, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	if requestTimeout <= 0 {
+		return ctx, func() {}, func() { cancel(nil) }
+	}
+
+	timer := time.AfterFunc(requestTimeout, func() {
+		cancel(fmt.Errorf("no progress for %s", requestTimeout))
+	})
+	progress := func() { timer.Reset(requestTimeout) }
+	return ctx, progress, func() {
+		timer.Stop()
+		cancel(nil)
+	}
+}
+
+// requestError replaces the error of a canceled request with the cancellation cause
+func requestError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	return err
+}
+
+// streamsFailed returns an error if every one of the total streams failed
+func streamsFailed(direction string, errs []error, total int) error {
+	if total == 0 || len(errs) < total {
+		return nil
+	}
+	return fmt.Errorf("all %d %s streams failed: %w", total, direction, errs[0])
+}
+
+// nothingTransferred returns an error if the streams of m transferred no data
+// at all, which would otherwise be reported as a speed of 0 bps
+func nothingTransferred(m *measurement) error {
+	if m.meter.BytesRead() > 0 {
+		return nil
+	}
+	return fmt.Errorf("no %s data was transferred within %s", m.direction, maxDuration)
+}
+
+func errorStrings(errs []error) (messages []string) {
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"mikkelam/fast-cli/mock"
+)
+
+// newMockPool returns a pool of targets served by a mock server at mock.DefaultRate
+func newMockPool(t *testing.T) *targetPool {
+	t.Helper()
+	srv := mock.NewServer(mock.DefaultRate)
+	t.Cleanup(srv.Close)
+
+	mockURL = srv.URL
+	display = noDisplay{}
+	previousDuration := maxDuration
+	maxDuration = 2 * time.Second
+	t.Cleanup(func() {
+		mockURL = ""
+		maxDuration = previousDuration
+	})
+
+	p, err := newProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates, err := getTargets(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets, _, _ := selectTargets(context.Background(), candidates, testServers, false)
+	return newTargetPool(p, targets)
+}
+
+// checkMockSpeed fails unless speed is within 20% of the mock rate
+func checkMockSpeed(t *testing.T, speed Speed) {
+	t.Helper()
+	if math.Abs(speed.bitsPerSec-mock.DefaultRate)/mock.DefaultRate > 0.2 {
+		t.Errorf("measured %.0f bps, want about %.0f", speed.bitsPerSec, mock.DefaultRate)
+	}
+	if speed.bytes == 0 || speed.elapsed <= 0 {
+		t.Errorf("measured %d bytes in %s, want both above 0", speed.bytes, speed.elapsed)
+	}
+	if len(speed.Errors) > 0 {
+		t.Errorf("stream errors: %v", speed.Errors)
+	}
+}
+
+func TestMeasureDownloadSpeed(t *testing.T) {
+	pool := newMockPool(t)
+	speed, err := measureDownloadSpeed(context.Background(), newTestClient(), pool, "download", pool.len(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMockSpeed(t, speed)
+}
+
+func TestMeasureUploadSpeed(t *testing.T) {
+	pool := newMockPool(t)
+	speed, err := measureUploadSpeed(context.Background(), newTestClient(), pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMockSpeed(t, speed)
+}
+
+func TestParseHeaders(t *testing.T) {
+	header, err := parseHeaders([]string{"X-Test: a", "x-test:b", "Authorization:  Bearer token "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := http.Header{"X-Test": {"a", "b"}, "Authorization": {"Bearer token"}}
+	if !reflect.DeepEqual(header, want) {
+		t.Errorf("parseHeaders() = %v, want %v", header, want)
+	}
+
+	for _, pair := range []string{"no colon", ": value", "Bad Key: value"} {
+		if _, err := parseHeaders([]string{pair}); err == nil {
+			t.Errorf("parseHeaders(%q) succeeded, want an error", pair)
+		}
+	}
+}
+
+func TestConsistency(t *testing.T) {
+	perSecond := int(time.Second / sampleInterval)
+	steady := make([]float64, 3*perSecond)
+	uneven := make([]float64, 2*perSecond)
+	for i := range steady {
+		steady[i] = 100
+	}
+	for i := range uneven {
+		uneven[i] = 100
+		if i >= perSecond {
+			uneven[i] = 300
+		}
+	}
+
+	tests := []struct {
+		name    string
+		samples []float64
+		score   float64
+		ok      bool
+	}{
+		{"steady", steady, 100, true},
+		{"uneven", uneven, 50, true},
+		{"too short", steady[:perSecond+1], 0, false},
+		{"no traffic", make([]float64, 2*perSecond), 0, false},
+	}
+	for _, tt := range tests {
+		score, ok := consistency(tt.samples)
+		if score != tt.score || ok != tt.ok {
+			t.Errorf("%s: consistency() = %v, %v, want %v, %v", tt.name, score, ok, tt.score, tt.ok)
+		}
+	}
+}
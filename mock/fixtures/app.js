@@ -0,0 +1 @@
+!function(e){var t={https:!0,token:"YXNkZmFzZGxmbnNkYWZoYXNkZmhrYWxm",urlCount:5};e.fastConfig=t}(window);
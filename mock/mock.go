@@ -0,0 +1,83 @@
+// Package mock serves recorded fast.com responses together with the speed test
+// endpoints of the server package, so that fast-cli can run without network access
+package mock
+
+import (
+	"embed"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"mikkelam/fast-cli/server"
+	"mikkelam/fast-cli/utils"
+)
+
+// DefaultRate is the throughput in bits per second the mock servers share
+const DefaultRate = 100e6
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// NewServer starts a server answering like fast.com, api.fast.com and the test
+// servers listed by the api. Downloads and uploads are throttled together to
+// bitsPerSec, so measurements against it give the same result every run.
+func NewServer(bitsPerSec float64) *httptest.Server {
+	limiter := utils.NewRateLimiter(bitsPerSec / 8)
+	endpoints := server.NewHandler()
+	speedtest := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = throttledReader{r.Body, limiter}
+		endpoints.ServeHTTP(throttledWriter{w, limiter}, r)
+	})
+
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.Handle(server.Path, speedtest)
+	mux.Handle(server.Path+"/", speedtest)
+	mux.HandleFunc("GET /{$}", serveFixture("fast.com.html", "text/html", nil))
+	mux.HandleFunc("GET /app-6e8cc1.js", serveFixture("app.js", "application/javascript", nil))
+	mux.HandleFunc("GET /netflix/speedtest/v2", serveFixture("api.json", "application/json", func(body string) string {
+		return strings.ReplaceAll(body, "{{server}}", srv.URL)
+	}))
+	srv = httptest.NewServer(mux)
+	return srv
+}
+
+// serveFixture answers with a recorded response, rewritten by rewrite if it is not nil
+func serveFixture(name string, contentType string, rewrite func(string) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := fixtures.ReadFile("fixtures/" + name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if rewrite != nil {
+			body = []byte(rewrite(string(body)))
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}
+}
+
+// throttledWriter holds back a response to the rate of its limiter
+type throttledWriter struct {
+	http.ResponseWriter
+	limiter *utils.RateLimiter
+}
+
+func (w throttledWriter) Write(p []byte) (int, error) {
+	w.limiter.Wait(len(p))
+	return w.ResponseWriter.Write(p)
+}
+
+// throttledReader holds back reading a request body to the rate of its limiter
+type throttledReader struct {
+	io.ReadCloser
+	limiter *utils.RateLimiter
+}
+
+func (r throttledReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.limiter.Wait(n)
+	return n, err
+}
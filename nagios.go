@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"mikkelam/fast-cli/utils"
+)
+
+// Plugin states and exit codes of a Nagios or Icinga check
+const (
+	nagiosOK       = 0
+	nagiosWarning  = 1
+	nagiosCritical = 2
+	nagiosUnknown  = 3
+)
+
+var nagiosStateNames = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// nagiosThresholds holds the rates, in bits per second, below which a speed
+// is a problem, zero disables the check of a direction
+type nagiosThresholds struct {
+	download float64
+	upload   float64
+}
+
+var (
+	warningThresholds  nagiosThresholds
+	criticalThresholds nagiosThresholds
+)
+
+// parseNagiosThresholds parses a rate that applies to the download and upload
+// speed alike, or separate rates for both such as "200Mbps,20Mbps"
+func parseNagiosThresholds(value string) (nagiosThresholds, error) {
+	if value == "" {
+		return nagiosThresholds{}, nil
+	}
+	download, upload, separate := strings.Cut(value, ",")
+	if !separate {
+		upload = download
+	}
+	var t nagiosThresholds
+	var err error
+	if t.download, err = utils.ParseBitRate(strings.TrimSpace(download)); err != nil {
+		return t, err
+	}
+	if t.upload, err = utils.ParseBitRate(strings.TrimSpace(upload)); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// nagiosCheck is the outcome of a check: its state, the problems that caused
+// it and the performance data
+type nagiosCheck struct {
+	state    int
+	summary  []string
+	problems []string
+	perfData []string
+}
+
+// checkSpeed compares a speed to the thresholds and records it
+func (c *nagiosCheck) checkSpeed(direction string, speed *Speed, warning, critical float64) {
+	if speed == nil {
+		return
+	}
+	c.summary = append(c.summary, fmt.Sprintf("%s %s", direction, nagiosRate(speed.bitsPerSec)))
+	switch {
+	case critical > 0 && speed.bitsPerSec < critical:
+		c.state = max(c.state, nagiosCritical)
+		c.problems = append(c.problems, fmt.Sprintf("%s below %s", direction, nagiosRate(critical)))
+	case warning > 0 && speed.bitsPerSec < warning:
+		c.state = max(c.state, nagiosWarning)
+		c.problems = append(c.problems, fmt.Sprintf("%s below %s", direction, nagiosRate(warning)))
+	}
+	c.perfData = append(c.perfData, fmt.Sprintf("%s=%.0f;%s;%s;0;", direction, speed.bitsPerSec,
+		nagiosRange(warning), nagiosRange(critical)))
+}
+
+// newNagiosCheck evaluates the results against the --warning and --critical thresholds
+func newNagiosCheck(results SpeedResults) nagiosCheck {
+	var c nagiosCheck
+	c.checkSpeed("download", results.Download, warningThresholds.download, criticalThresholds.download)
+	c.checkSpeed("upload", results.Upload, warningThresholds.upload, criticalThresholds.upload)
+	latency := strconv.FormatFloat(results.LatencyMs, 'f', -1, 64)
+	if len(c.summary) == 0 {
+		c.summary = append(c.summary, fmt.Sprintf("latency %sms", latency))
+	}
+	c.perfData = append(c.perfData, fmt.Sprintf("latency=%sms;;;0;", latency))
+	return c
+}
+
+// err returns an error carrying the state of a failed check, so that it determines the exit code
+func (c nagiosCheck) err() error {
+	switch c.state {
+	case nagiosCritical:
+		return withCodef(codeBelowCritical, "%s", strings.Join(c.problems, ", "))
+	case nagiosWarning:
+		return withCodef(codeBelowWarning, "%s", strings.Join(c.problems, ", "))
+	}
+	return nil
+}
+
+// writeNagios prints the results as plugin output, e.g.
+// "OK - download 412Mbps | download=412000000;200000000:;50000000:;0; latency=9.1ms;;;0;"
+func writeNagios(w io.Writer, results SpeedResults) {
+	c := newNagiosCheck(results)
+	status := strings.Join(c.summary, ", ")
+	if len(c.problems) > 0 {
+		status += " (" + strings.Join(c.problems, ", ") + ")"
+	}
+	fmt.Fprintf(w, "%s - %s | %s\n", nagiosStateNames[c.state], status, strings.Join(c.perfData, " "))
+}
+
+// nagiosState returns the plugin state for the error a check ended with
+func nagiosState(err error) int {
+	if err == nil {
+		return nagiosOK
+	}
+	switch errorCode(err) {
+	case codeBelowWarning, codeBelowExpected:
+		return nagiosWarning
+	case codeBelowCritical, codeNetworkUnreachable, codeCaptivePortal:
+		return nagiosCritical
+	}
+	return nagiosUnknown
+}
+
+// nagiosRate formats a rate in bits per second as megabits per second
+func nagiosRate(bitsPerSec float64) string {
+	return strconv.FormatFloat(math.Round(bitsPerSec/1e5)/10, 'f', -1, 64) + "Mbps"
+}
+
+// nagiosRange returns the threshold range that alerts on values below rate
+func nagiosRange(rate float64) string {
+	if rate <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f:", rate)
+}
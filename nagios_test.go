@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestParseNagiosThresholds(t *testing.T) {
+	tests := []struct {
+		value string
+		want  nagiosThresholds
+	}{
+		{"", nagiosThresholds{}},
+		{"200Mbps", nagiosThresholds{download: 200e6, upload: 200e6}},
+		{"200Mbps, 20Mbps", nagiosThresholds{download: 200e6, upload: 20e6}},
+		{"1Gbps,0", nagiosThresholds{download: 1e9}},
+	}
+	for _, tt := range tests {
+		got, err := parseNagiosThresholds(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseNagiosThresholds(%q) = %+v, %v, want %+v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"fast", "200Mbps,slow", ","} {
+		if _, err := parseNagiosThresholds(value); err == nil {
+			t.Errorf("parseNagiosThresholds(%q) succeeded, want an error", value)
+		}
+	}
+}
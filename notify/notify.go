@@ -0,0 +1,18 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// run executes a notification command. The title and message are handed over
+// through the environment so they never need to be quoted into a script.
+func run(title string, message string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), "FAST_CLI_TITLE="+title, "FAST_CLI_MESSAGE="+message)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, output)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package notify
+
+const script = `display notification (system attribute "FAST_CLI_MESSAGE") with title (system attribute "FAST_CLI_TITLE")`
+
+// Send shows a notification through the macOS notification center
+func Send(title string, message string) error {
+	return run(title, message, "osascript", "-e", script)
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !freebsd && !openbsd && !netbsd && !dragonfly && !darwin && !windows
+
+package notify
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Send reports that notifications are not supported on this platform
+func Send(title string, message string) error {
+	return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,16 @@
+package notify
+
+const script = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$texts = $template.GetElementsByTagName('text')
+$texts.Item(0).AppendChild($template.CreateTextNode($env:FAST_CLI_TITLE)) > $null
+$texts.Item(1).AppendChild($template.CreateTextNode($env:FAST_CLI_MESSAGE)) > $null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('fast-cli').Show($toast)
+`
+
+// Send shows a Windows toast notification through PowerShell
+func Send(title string, message string) error {
+	return run(title, message, "powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+}
@@ -0,0 +1,8 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package notify
+
+// Send shows a desktop notification through notify-send
+func Send(title string, message string) error {
+	return run(title, message, "notify-send", "--app-name=fast-cli", title, message)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// Output formats selected with --format
+const (
+	formatText      = "text"
+	formatJSON      = "json"
+	formatOoklaJSON = "ookla-json"
+	formatNagios    = "nagios"
+)
+
+var outputFormats = []string{formatText, formatJSON, formatOoklaJSON, formatNagios}
+
+// ooklaResult mirrors the result printed by Ookla's speedtest CLI with
+// --format=json, so tools reading that output can read fast-cli's as well
+type ooklaResult struct {
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Ping      ooklaPing       `json:"ping"`
+	Download  *ooklaTransfer  `json:"download,omitempty"`
+	Upload    *ooklaTransfer  `json:"upload,omitempty"`
+	ISP       string          `json:"isp"`
+	Interface ooklaInterface  `json:"interface"`
+	Server    ooklaServer     `json:"server"`
+	Result    ooklaResultInfo `json:"result"`
+}
+
+type ooklaPing struct {
+	Jitter  float64 `json:"jitter"`
+	Latency float64 `json:"latency"`
+}
+
+type ooklaTransfer struct {
+	// Bandwidth is in bytes per second
+	Bandwidth int64 `json:"bandwidth"`
+	Bytes     int64 `json:"bytes"`
+	// Elapsed is in milliseconds
+	Elapsed int64 `json:"elapsed"`
+}
+
+type ooklaInterface struct {
+	InternalIP string `json:"internalIp"`
+	Name       string `json:"name"`
+	MacAddr    string `json:"macAddr"`
+	IsVpn      bool   `json:"isVpn"`
+	ExternalIP string `json:"externalIp"`
+}
+
+type ooklaServer struct {
+	ID       int    `json:"id"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Country  string `json:"country"`
+	IP       string `json:"ip"`
+}
+
+type ooklaResultInfo struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	Persisted bool   `json:"persisted"`
+}
+
+// ooklaResults converts the results to the Ookla speedtest CLI schema. Fields
+// fast-cli does not measure, such as the jitter, are left at their zero value.
+func ooklaResults(results SpeedResults) ooklaResult {
+	result := ooklaResult{
+		Type:      "result",
+		Timestamp: results.timestamp.UTC().Truncate(time.Second),
+		Ping:      ooklaPing{Latency: results.LatencyMs},
+		Download:  ooklaSpeed(results.Download),
+		Upload:    ooklaSpeed(results.Upload),
+		ISP:       results.Metadata.ISP,
+		Interface: ooklaInterface{ExternalIP: results.Metadata.ClientIP},
+	}
+
+	if len(results.Servers) > 0 {
+		server := results.Servers[0]
+		result.Server = ooklaServer{Host: server.Host, Name: server.Host, Port: server.port}
+		if server.POP != "" {
+			result.Server.Name = server.POP
+		}
+		if len(server.IPs) > 0 {
+			result.Server.IP = server.IPs[0]
+			result.Interface.InternalIP = localAddress(server.IPs[0])
+		}
+		city, country, found := strings.Cut(server.Location, ", ")
+		result.Server.Location = city
+		if found {
+			result.Server.Country = country
+		}
+	}
+	return result
+}
+
+func ooklaSpeed(speed *Speed) *ooklaTransfer {
+	if speed == nil {
+		return nil
+	}
+	return &ooklaTransfer{
+		Bandwidth: int64(speed.bitsPerSec / 8),
+		Bytes:     int64(speed.bytes),
+		Elapsed:   speed.elapsed.Milliseconds(),
+	}
+}
+
+// localAddress returns the address of the interface used to reach ip. Dialing
+// UDP only picks the route, it does not send anything.
+func localAddress(ip string) string {
+	conn, err := net.Dial("udp", net.JoinHostPort(ip, "80"))
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return addr.IP.String()
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mikkelam/fast-cli/cloudflare"
+	"mikkelam/fast-cli/fast"
+	"mikkelam/fast-cli/provider"
+	"mikkelam/fast-cli/utils"
+)
+
+// preflightTimeout bounds each of the pre-flight checks
+const preflightTimeout = 5 * time.Second
+
+// probeSize is the number of bytes downloaded by the pre-flight probe
+const probeSize = 16 * 1024
+
+// apiURL returns the URL the provider fetches its test servers from
+func apiURL(p provider.Provider) string {
+	switch p := p.(type) {
+	case *fast.Provider:
+		return p.GetDefaultURL()
+	case *cloudflare.Provider:
+		return cloudflare.BaseURL
+	}
+	if urls := customURLs.Value(); len(urls) > 0 {
+		return urls[0]
+	}
+	return ""
+}
+
+// report receives the outcome of each successful check
+type report func(format string, a ...any)
+
+// checkEndpoint verifies that the host of rawURL resolves and, for https URLs,
+// accepts a TLS connection with a valid certificate
+func checkEndpoint(ctx context.Context, rawURL string, report report) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return withCode(codeInvalidArgument, err)
+	}
+	host := u.Hostname()
+
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return withCodef(codeNetworkUnreachable, "pre-flight: could not resolve %s: %w", host, err)
+	}
+	report("   DNS:      %s resolves to %s (%.0f ms)\n", host, strings.Join(addrs, ", "), milliseconds(time.Since(start)))
+
+	if u.Scheme != "https" {
+		report("   TLS:      skipped, %s is not using https\n", host)
+		return nil
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	start = time.Now()
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			return withCodef(codeCaptivePortal, "pre-flight: the certificate of %s is not valid, a captive portal or proxy may be intercepting the connection: %w", host, err)
+		}
+		return withCodef(codeNetworkUnreachable, "pre-flight: could not establish a TLS connection to %s: %w", host, err)
+	}
+	conn.Close()
+	report("   TLS:      %s certificate is valid (%.0f ms)\n", host, milliseconds(time.Since(start)))
+	return nil
+}
+
+// probeTarget downloads a few bytes from target and checks that they are not a
+// web page, which is what captive portals answer every request with
+func probeTarget(ctx context.Context, client *http.Client, p provider.Provider, target provider.Target, report report) error {
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, p.DownloadURL(target, probeSize), nil)
+	if err != nil {
+		return withCode(codeInvalidArgument, err)
+	}
+	setRequestHeaders(request)
+
+	start := time.Now()
+	resp, err := client.Do(request)
+	if err != nil {
+		return withCodef(failureCode(codeDownloadFailed, err), "pre-flight: probe download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if host := resp.Request.URL.Host; host != request.URL.Host {
+		return withCodef(codeCaptivePortal, "pre-flight: the probe download was redirected to %s, you may be behind a captive portal", host)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return withCodef(codeDownloadFailed, "pre-flight: probe download returned %s", resp.Status)
+	}
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(resp.Body, head)
+	if isWebPage(resp.Header.Get("Content-Type"), head[:n]) {
+		return withCodef(codeCaptivePortal, "pre-flight: the probe download returned a web page instead of test data, you may be behind a captive portal")
+	}
+	rest, err := io.Copy(io.Discard, io.LimitReader(resp.Body, probeSize-int64(n)))
+	if err != nil {
+		return withCodef(codeDownloadFailed, "pre-flight: probe download failed: %w", err)
+	}
+	report("   Probe:    %s from %s (%.0f ms)\n", strings.TrimSpace(utils.Bytes(uint64(int64(n)+rest))),
+		request.URL.Host, milliseconds(time.Since(start)))
+	return nil
+}
+
+// isWebPage reports whether a response with the given content type and first
+// bytes is an HTML page
+func isWebPage(contentType string, head []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "text/html" {
+		return true
+	}
+	return strings.HasPrefix(http.DetectContentType(head), "text/html")
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"mikkelam/fast-cli/utils"
+)
+
+// sampleInterval is how often the throughput is sampled and the progress redrawn
+const sampleInterval = 100 * time.Millisecond
+
+// Like fast.com, a test starts with one stream and adds another every
+// rampInterval for as long as the throughput grows by at least rampGrowth
+const (
+	rampInterval = 500 * time.Millisecond
+	rampGrowth   = 1.1
+)
+
+// A stream failing before the test is over is restarted on the next server
+// after restartDelay, up to maxStreamRestarts times
+const (
+	maxStreamRestarts = 3
+	restartDelay      = 250 * time.Millisecond
+)
+
+var spinnerStates = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// asciiSpinnerStates replace spinnerStates on terminals without Unicode support
+var asciiSpinnerStates = []string{"|", "/", "-", "\\"}
+
+// measurement is the state of a running download or upload test
+type measurement struct {
+	direction string
+	meter     utils.BandwidthMeter
+	streams   []*utils.BandwidthMeter
+	// limiter throttles all streams together, nil without --limit
+	limiter *utils.RateLimiter
+	// started is the number of streams started so far
+	started int
+	// restarts counts how often each stream was restarted after failing
+	restarts []int
+	// ramp adds streams one by one while the throughput grows, otherwise all start at once
+	ramp bool
+	// samples holds the throughput in bits per second over each sampleInterval
+	samples   []float64
+	lastBytes uint64
+}
+
+func newMeasurement(direction string, streams int) *measurement {
+	m := &measurement{
+		direction: direction,
+		samples:   make([]float64, 0, int(maxDuration/sampleInterval)+1),
+		restarts:  make([]int, streams),
+		ramp:      true,
+	}
+	if limitRate > 0 {
+		m.limiter = utils.NewRateLimiter(limitRate / 8)
+	}
+	m.meter.Start()
+	for i := 0; i < streams; i++ {
+		meter := &utils.BandwidthMeter{}
+		meter.Start()
+		m.streams = append(m.streams, meter)
+	}
+	return m
+}
+
+// streamWriter returns the writer a stream reports its transferred bytes to
+func (m *measurement) streamWriter(stream int) io.Writer {
+	if m.limiter != nil {
+		// Blocking in the limiter before counting holds back the stream itself
+		return io.MultiWriter(m.limiter, &m.meter, m.streams[stream])
+	}
+	return io.MultiWriter(&m.meter, m.streams[stream])
+}
+
+// streamResult is sent by a stream when it ends, err is nil unless it failed
+type streamResult struct {
+	stream int
+	err    error
+}
+
+// sample records the throughput since the previous sample
+func (m *measurement) sample(interval time.Duration) {
+	bytes := m.meter.BytesRead()
+	m.samples = append(m.samples, float64(bytes-m.lastBytes)*8/interval.Seconds())
+	m.lastBytes = bytes
+}
+
+// progressDisplay renders the state of running measurements
+type progressDisplay interface {
+	// start is called before a measurement begins
+	start(m *measurement)
+	// update is called every sampleInterval, fraction is the completed part of the test
+	update(m *measurement, elapsed time.Duration, fraction float64)
+	// finish is called once the measurement completed or timed out
+	finish(m *measurement)
+	// showLatency is called once the unloaded latency has been measured
+	showLatency(latency time.Duration)
+	// close is called after all measurements are done
+	close()
+}
+
+// newProgressDisplay returns the display matching the output flags
+func newProgressDisplay() progressDisplay {
+	switch {
+	case tuiMode:
+		return newTUIDisplay()
+	case simpleProgress && intervalOutput > 0:
+		return &intervalDisplay{every: intervalOutput}
+	case simpleProgress:
+		return noDisplay{}
+	default:
+		return &lineDisplay{}
+	}
+}
+
+// noDisplay shows nothing until the final result
+type noDisplay struct{}
+
+func (noDisplay) start(m *measurement)                                           {}
+func (noDisplay) update(m *measurement, elapsed time.Duration, fraction float64) {}
+func (noDisplay) finish(m *measurement)                                          {}
+func (noDisplay) showLatency(latency time.Duration)                              {}
+func (noDisplay) close()                                                         {}
+
+// intervalDisplay prints a timestamped line with the average speed so far
+// every interval, for logs that cannot handle a line redrawn in place
+type intervalDisplay struct {
+	every time.Duration
+	next  time.Duration
+}
+
+func (d *intervalDisplay) start(m *measurement) {
+	d.next = d.every
+}
+
+func (d *intervalDisplay) update(m *measurement, elapsed time.Duration, fraction float64) {
+	if elapsed < d.next {
+		return
+	}
+	for d.next <= elapsed {
+		d.next += d.every
+	}
+	utils.Statusf("%s %s %ss %s average\n", time.Now().Format(time.RFC3339), m.direction,
+		utils.Decimal(elapsed.Seconds(), 1), strings.TrimSpace(utils.BitsPerSec(m.meter.Bandwidth())))
+}
+
+func (d *intervalDisplay) finish(m *measurement)             {}
+func (d *intervalDisplay) showLatency(latency time.Duration) {}
+func (d *intervalDisplay) close()                            {}
+
+// lineDisplay redraws a single progress bar line in place
+type lineDisplay struct {
+	spinnerIndex int
+	elapsed      time.Duration
+	fraction     float64
+}
+
+func (d *lineDisplay) start(m *measurement) {
+	icon := utils.Symbol("⬇️ ", "")
+	if m.direction == "upload" {
+		icon = utils.Symbol("⬆️ ", "")
+	}
+	utils.Statusf("%sEstimating %s speed...\n", icon, m.direction)
+}
+
+func (d *lineDisplay) update(m *measurement, elapsed time.Duration, fraction float64) {
+	states := spinnerStates
+	if utils.AppConfig.ASCII {
+		states = asciiSpinnerStates
+	}
+	spinner := states[d.spinnerIndex%len(states)]
+	d.spinnerIndex = (d.spinnerIndex + 1) % len(states)
+	d.elapsed, d.fraction = elapsed, fraction
+	d.render(m, spinner, false)
+}
+
+// finish redraws the line once more as done, keeping the bar where it was when
+// the streams completed before the maximum duration
+func (d *lineDisplay) finish(m *measurement) {
+	d.render(m, utils.Symbol("✓", "+"), true)
+	utils.Statusln()
+}
+
+func (d *lineDisplay) render(m *measurement, spinner string, done bool) {
+	line := utils.ProgressLine{
+		Spinner:    spinner,
+		Fraction:   d.fraction,
+		Elapsed:    d.elapsed,
+		Bytes:      m.meter.BytesRead(),
+		BitsPerSec: m.meter.Bandwidth() * 8,
+		Thresholds: thresholds,
+		Done:       done,
+	}
+	// Clear to the end of the line so a shorter redraw leaves nothing behind
+	utils.Statusf("\r%s\033[K", line.Render(utils.TerminalWidth(os.Stderr)))
+}
+
+func (d *lineDisplay) showLatency(latency time.Duration) {}
+
+func (d *lineDisplay) close() {}
+
+// monitorProgress starts the streams and renders progress until the test times
+// out, ctx is canceled or all started streams complete, and returns the errors
+// of the streams that failed for good. startStream is called again for a failed
+// stream with the number of restarts so far as attempt.
+func monitorProgress(ctx context.Context, m *measurement, maxDuration time.Duration, completed chan streamResult, startStream func(stream int, attempt int)) (errs []error) {
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(maxDuration)
+	start := time.Now()
+	completeCount := 0
+
+	display.start(m)
+	defer display.finish(m)
+
+	startStream(0, 0)
+	m.started = 1
+	for !m.ramp && m.started < len(m.streams) {
+		startStream(m.started, 0)
+		m.started++
+	}
+	lastRamp, rampBytes, rampSpeed := start, uint64(0), 0.0
+
+	for {
+		select {
+		case <-timeout:
+			display.update(m, time.Since(start), 1)
+			return errs
+
+		case <-ctx.Done():
+			return errs
+
+		case <-ticker.C:
+			m.sample(sampleInterval)
+			elapsed := time.Since(start)
+			display.update(m, elapsed, elapsed.Seconds()/maxDuration.Seconds())
+
+			if since := time.Since(lastRamp); m.started < len(m.streams) && since >= rampInterval {
+				bytes := m.meter.BytesRead()
+				speed := float64(bytes-rampBytes) / since.Seconds()
+				if speed > rampSpeed*rampGrowth {
+					slog.Debug("adding stream", "direction", m.direction, "stream", m.started, "speed", speed*8)
+					startStream(m.started, 0)
+					m.started++
+				}
+				lastRamp, rampBytes, rampSpeed = time.Now(), bytes, speed
+			}
+
+		case result := <-completed:
+			if result.err != nil && m.restarts[result.stream] < maxStreamRestarts {
+				m.restarts[result.stream]++
+				stream, attempt := result.stream, m.restarts[result.stream]
+				slog.Info("restarting failed stream", "direction", m.direction, "stream", stream,
+					"attempt", attempt, "error", result.err)
+				time.AfterFunc(restartDelay, func() { startStream(stream, attempt) })
+				continue
+			}
+			completeCount++
+			if result.err != nil {
+				slog.Debug("stream failed", "error", result.err)
+				errs = append(errs, result.err)
+			}
+			if completeCount == m.started {
+				elapsed := time.Since(start)
+				display.update(m, elapsed, elapsed.Seconds()/maxDuration.Seconds())
+				return errs
+			}
+		}
+	}
+}
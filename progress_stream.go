@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"mikkelam/fast-cli/sse"
+)
+
+// progressStreamPath is where --progress-listen serves the progress events
+const progressStreamPath = "/events"
+
+// progressStreamShutdown bounds the time spent sending the last events to clients on exit
+const progressStreamShutdown = time.Second
+
+// progressEvent describes the state of a measurement in a streamed event
+type progressEvent struct {
+	Direction  string  `json:"direction"`
+	Elapsed    float64 `json:"elapsed_seconds"`
+	Fraction   float64 `json:"fraction,omitempty"`
+	Bytes      uint64  `json:"bytes"`
+	BitsPerSec float64 `json:"bits_per_sec"`
+	Streams    int     `json:"streams"`
+}
+
+type latencyEvent struct {
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+func newProgressEvent(m *measurement, elapsed time.Duration, fraction float64) progressEvent {
+	return progressEvent{
+		Direction:  m.direction,
+		Elapsed:    elapsed.Seconds(),
+		Fraction:   fraction,
+		Bytes:      m.meter.BytesRead(),
+		BitsPerSec: m.meter.Bandwidth() * 8,
+		Streams:    m.started,
+	}
+}
+
+// eventDisplay publishes the progress of the measurements to a broker
+// alongside the regular display
+type eventDisplay struct {
+	progressDisplay
+	broker  *sse.Broker
+	elapsed time.Duration
+}
+
+func (d *eventDisplay) start(m *measurement) {
+	d.elapsed = 0
+	d.broker.Publish("start", newProgressEvent(m, 0, 0))
+	d.progressDisplay.start(m)
+}
+
+func (d *eventDisplay) update(m *measurement, elapsed time.Duration, fraction float64) {
+	d.elapsed = elapsed
+	d.broker.Publish("progress", newProgressEvent(m, elapsed, fraction))
+	d.progressDisplay.update(m, elapsed, fraction)
+}
+
+func (d *eventDisplay) finish(m *measurement) {
+	d.broker.Publish("finish", newProgressEvent(m, d.elapsed, 1))
+	d.progressDisplay.finish(m)
+}
+
+func (d *eventDisplay) showLatency(latency time.Duration) {
+	d.broker.Publish("latency", latencyEvent{LatencyMs: milliseconds(latency)})
+	d.progressDisplay.showLatency(latency)
+}
+
+// serveProgress streams the events published to the returned broker on addr
+// until stop is called, which lets connected clients receive the last events first
+func serveProgress(addr string) (broker *sse.Broker, stop func(), err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	broker = sse.NewBroker()
+	mux := http.NewServeMux()
+	mux.Handle("GET "+progressStreamPath, broker)
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("progress stream stopped", "error", err)
+		}
+	}()
+	slog.Info("streaming progress events", "addr", listener.Addr().String())
+
+	stop = func() {
+		broker.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), progressStreamShutdown)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			srv.Close()
+		}
+	}
+	return broker, stop, nil
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Target is a single server endpoint used for measuring throughput
+type Target struct {
+	// URL is requested with GET to measure download speed
+	URL string
+	// UploadURL receives POST requests to measure upload speed
+	UploadURL string
+	// Location is a human readable description of where the server is, if known
+	Location string
+}
+
+// Metadata describes the test as seen by the provider
+type Metadata struct {
+	Provider string `json:"provider"`
+	ClientIP string `json:"client_ip,omitempty"`
+	ISP      string `json:"isp,omitempty"`
+	Location string `json:"location,omitempty"`
+	Server   string `json:"server,omitempty"`
+}
+
+// Provider is a speed test backend handing out servers to measure against
+type Provider interface {
+	// Name returns the short name used to select the provider
+	Name() string
+	// GetTargets returns up to count servers to run the test against
+	GetTargets(ctx context.Context, count int) ([]Target, error)
+	// SupportsUpload reports whether the targets accept upload requests
+	SupportsUpload() bool
+	// Metadata returns information gathered while fetching the targets
+	Metadata() Metadata
+	// DownloadURL returns a URL downloading size bytes from target, or the
+	// target URL when the provider cannot size downloads
+	DownloadURL(target Target, size int64) string
+}
+
+// RangeURL returns a fast.com style ranged URL downloading the first size bytes
+// of rawURL, which puts /range/0-<size-1> at the end of its path
+func RangeURL(rawURL string, size int64) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || size <= 0 {
+		return rawURL
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + fmt.Sprintf("/range/0-%d", size-1)
+	return u.String()
+}
@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// Static is a provider serving a fixed list of URLs, such as a fast-cli serve-target
+type Static struct {
+	urls []string
+}
+
+// NewStatic returns a provider that tests against the given URLs
+func NewStatic(urls []string) *Static {
+	return &Static{urls: urls}
+}
+
+// Name returns the provider name
+func (s *Static) Name() string {
+	return "custom"
+}
+
+// GetTargets returns one target per configured URL, repeating them until count is reached
+func (s *Static) GetTargets(ctx context.Context, count int) (targets []Target, err error) {
+	for i := 0; i < max(count, len(s.urls)); i++ {
+		url := s.urls[i%len(s.urls)]
+		targets = append(targets, Target{URL: url, UploadURL: url})
+	}
+	return
+}
+
+// SupportsUpload reports that static targets accept uploads
+func (s *Static) SupportsUpload() bool {
+	return true
+}
+
+// Metadata returns the provider metadata
+func (s *Static) Metadata() Metadata {
+	return Metadata{Provider: s.Name()}
+}
+
+// DownloadURL returns a ranged URL for fast.com compatible endpoints such as a
+// fast-cli serve-target, other URLs are downloaded as they are
+func (s *Static) DownloadURL(target Target, size int64) string {
+	if u, err := url.Parse(target.URL); err == nil && strings.HasSuffix(u.Path, "/speedtest") {
+		return RangeURL(target.URL, size)
+	}
+	return target.URL
+}
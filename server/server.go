@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultPayloadSize is the number of bytes served by a plain download request
+const DefaultPayloadSize = 26214400 // 25 MB
+
+// MaxPayloadSize caps the size of a single ranged download request
+const MaxPayloadSize = 1 << 30 // 1 GB
+
+// Path is the endpoint path clients should point at, it mirrors the fast.com layout
+const Path = "/speedtest"
+
+// payload is written repeatedly to build download responses. It is filled with
+// random data so that compressing middleboxes cannot skew the measurement.
+var payload = make([]byte, 1024*1024)
+
+func init() {
+	_, _ = rand.Read(payload)
+}
+
+// NewHandler returns a handler serving fast.com compatible speed test endpoints.
+//
+//	GET  /speedtest                 downloads DefaultPayloadSize bytes
+//	GET  /speedtest/range/0-1048575 downloads the requested range
+//	POST /speedtest[/range/...]     discards the request body
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+Path, func(w http.ResponseWriter, r *http.Request) {
+		serveDownload(w, DefaultPayloadSize)
+	})
+	mux.HandleFunc("GET "+Path+"/range/{range}", func(w http.ResponseWriter, r *http.Request) {
+		size, err := parseRange(r.PathValue("range"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		serveDownload(w, size)
+	})
+	mux.HandleFunc("POST "+Path, serveUpload)
+	mux.HandleFunc("POST "+Path+"/range/{range}", serveUpload)
+	return mux
+}
+
+// ListenAndServe serves the speed test endpoints on addr until it fails or ctx is canceled
+func ListenAndServe(ctx context.Context, addr string) error {
+	slog.Info("serving speed test target", "addr", addr)
+	srv := &http.Server{Addr: addr, Handler: NewHandler()}
+	stop := context.AfterFunc(ctx, func() { srv.Close() })
+	defer stop()
+
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func serveDownload(w http.ResponseWriter, size int64) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+
+	for remaining := size; remaining > 0; {
+		chunk := payload[:min(remaining, int64(len(payload)))]
+		n, err := w.Write(chunk)
+		if err != nil {
+			return
+		}
+		remaining -= int64(n)
+	}
+}
+
+func serveUpload(w http.ResponseWriter, r *http.Request) {
+	n, err := io.Copy(io.Discard, r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	slog.Debug("received upload", "bytes", n, "remote", r.RemoteAddr)
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseRange parses a fast.com style "start-end" byte range and returns its length
+func parseRange(value string) (int64, error) {
+	startText, endText, found := strings.Cut(value, "-")
+	if !found {
+		return 0, fmt.Errorf("invalid range %q", value)
+	}
+	start, err := strconv.ParseInt(startText, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid range start %q", startText)
+	}
+	end, err := strconv.ParseInt(endText, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid range end %q", endText)
+	}
+	size := end - start + 1
+	if start < 0 || size <= 0 || size > MaxPayloadSize {
+		return 0, fmt.Errorf("invalid range %q", value)
+	}
+	return size, nil
+}
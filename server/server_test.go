@@ -0,0 +1,30 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		value string
+		size  int64
+	}{
+		{"0-0", 1},
+		{"0-26214399", 26214400},
+		{"100-199", 100},
+		{"0-" + strconv.FormatInt(MaxPayloadSize-1, 10), MaxPayloadSize},
+	}
+	for _, tt := range tests {
+		size, err := parseRange(tt.value)
+		if err != nil || size != tt.size {
+			t.Errorf("parseRange(%q) = %d, %v, want %d", tt.value, size, err, tt.size)
+		}
+	}
+
+	for _, value := range []string{"", "100", "a-100", "0-b", "10-5", "-1-5", "0-" + strconv.FormatInt(MaxPayloadSize, 10)} {
+		if _, err := parseRange(value); err == nil {
+			t.Errorf("parseRange(%q) succeeded, want an error", value)
+		}
+	}
+}
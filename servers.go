@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mikkelam/fast-cli/fast"
+	"mikkelam/fast-cli/provider"
+)
+
+// resolveTimeout bounds resolving the addresses of the test servers
+const resolveTimeout = 2 * time.Second
+
+// Server is one of the hosts the test ran against
+type Server struct {
+	Host string   `json:"host"`
+	IPs  []string `json:"ips,omitempty"`
+	// POP is the CDN point of presence, parsed from fast.com hostnames
+	POP      string `json:"pop,omitempty"`
+	Location string `json:"location,omitempty"`
+
+	port int
+}
+
+// String describes the server by its POP, or its host if unknown, followed by its addresses
+func (s Server) String() string {
+	name := s.POP
+	if name == "" {
+		name = s.Host
+	}
+	if len(s.IPs) == 0 || (len(s.IPs) == 1 && s.IPs[0] == name) {
+		return name
+	}
+	return name + " (" + strings.Join(s.IPs, ", ") + ")"
+}
+
+// describeServers resolves the addresses of the distinct hosts of the targets in parallel
+func describeServers(ctx context.Context, targets []provider.Target) []Server {
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	locations, ports := map[string]string{}, map[string]int{}
+	for _, target := range targets {
+		u, err := url.Parse(target.URL)
+		if err != nil || ports[u.Hostname()] != 0 {
+			continue
+		}
+		locations[u.Hostname()] = target.Location
+		ports[u.Hostname()] = urlPort(u)
+	}
+
+	hosts := targetHosts(targets)
+	servers := make([]Server, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			servers[i] = Server{Host: host, POP: fast.POP(host), Location: locations[host], port: ports[host]}
+			if ip := net.ParseIP(host); ip != nil {
+				servers[i].IPs = []string{ip.String()}
+				return
+			}
+			servers[i].IPs, _ = net.DefaultResolver.LookupHost(ctx, host)
+		}()
+	}
+	wg.Wait()
+	return servers
+}
+
+// urlPort returns the port of u, or the default port of its scheme
+func urlPort(u *url.URL) int {
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		return port
+	}
+	if u.Scheme == "http" {
+		return 80
+	}
+	return 443
+}
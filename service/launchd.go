@@ -0,0 +1,57 @@
+package service
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// launchdPlan installs a launch agent started every interval
+func launchdPlan(spec Spec, home string) Plan {
+	label := "com.github.mikkelam." + spec.Name
+	path := filepath.Join(home, "Library", "LaunchAgents", label+".plist")
+	logPath := filepath.Join(home, "Library", "Logs", spec.Name+".log")
+
+	var arguments strings.Builder
+	for _, arg := range append([]string{spec.Executable}, spec.Args...) {
+		fmt.Fprintf(&arguments, "\t\t<string>%s</string>\n", plistEscape(arg))
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>StartInterval</key>
+	<integer>%d</integer>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%[4]s</string>
+</dict>
+</plist>
+`, label, arguments.String(), int(spec.Interval.Seconds()), plistEscape(logPath))
+
+	return Plan{
+		Files: []File{{Path: path, Content: plist}},
+		Commands: []Command{
+			// Unloading first replaces an agent installed before
+			{Args: []string{"launchctl", "unload", path}, IgnoreError: true},
+			{Args: []string{"launchctl", "load", "-w", path}},
+		},
+		Note: fmt.Sprintf("Results are written to %s.", logPath),
+	}
+}
+
+func plistEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schtasksPlan creates a scheduled task with the Windows task scheduler
+func schtasksPlan(spec Spec) (Plan, error) {
+	if spec.Interval%time.Minute != 0 {
+		return Plan{}, fmt.Errorf("the task scheduler runs tasks in whole minutes, got %s", spec.Interval)
+	}
+	schedule, modifier := "MINUTE", int(spec.Interval/time.Minute)
+	if spec.Interval >= 24*time.Hour {
+		if spec.Interval%(24*time.Hour) != 0 {
+			return Plan{}, fmt.Errorf("intervals of a day or more must be whole days, got %s", spec.Interval)
+		}
+		schedule, modifier = "DAILY", int(spec.Interval/(24*time.Hour))
+	}
+
+	command := []string{windowsQuote(spec.Executable)}
+	for _, arg := range spec.Args {
+		command = append(command, windowsQuote(arg))
+	}
+
+	return Plan{
+		Commands: []Command{{Args: []string{"schtasks", "/Create", "/F", "/TN", spec.Name,
+			"/SC", schedule, "/MO", strconv.Itoa(modifier), "/TR", strings.Join(command, " ")}}},
+		Note: "The output of scheduled tasks is discarded, add --append to keep the results.",
+	}, nil
+}
+
+// windowsQuote quotes an argument for the command line of a Windows program
+func windowsQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+	return `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+}
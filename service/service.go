@@ -0,0 +1,101 @@
+// Package service installs fast-cli as a task run periodically by the system scheduler
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Spec describes the scheduled task
+type Spec struct {
+	// Name identifies the task, e.g. the systemd unit name
+	Name string
+	// Executable is the absolute path of the fast-cli binary
+	Executable string
+	// Args are passed to fast-cli on every run
+	Args     []string
+	Interval time.Duration
+}
+
+// File is a configuration file written for the task
+type File struct {
+	Path    string
+	Content string
+}
+
+// Command is run after the files are written
+type Command struct {
+	Args []string
+	// IgnoreError is set for cleanup commands that fail when nothing was installed before
+	IgnoreError bool
+}
+
+// Plan is everything Install does to set up a task
+type Plan struct {
+	Files    []File
+	Commands []Command
+	// Note is shown after the task was installed
+	Note string
+}
+
+// NewPlan returns the plan installing spec with the scheduler of this platform
+func NewPlan(spec Spec) (Plan, error) {
+	// The name becomes part of the paths of the files written for the task
+	if spec.Name == "" || strings.ContainsAny(spec.Name, `/\`) || strings.Contains(spec.Name, "..") {
+		return Plan{}, fmt.Errorf("invalid name %q, it must not be empty or contain path separators or ..", spec.Name)
+	}
+	if spec.Interval < time.Minute {
+		return Plan{}, fmt.Errorf("the interval must be at least a minute, got %s", spec.Interval)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		return systemdPlan(spec, filepath.Join(home, ".config", "systemd", "user")), nil
+	case "darwin":
+		return launchdPlan(spec, home), nil
+	case "windows":
+		return schtasksPlan(spec)
+	default:
+		return Plan{}, fmt.Errorf("installing a service is not supported on %s", runtime.GOOS)
+	}
+}
+
+// Install writes the files of plan and runs its commands
+func Install(plan Plan) error {
+	for _, file := range plan.Files {
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file.Path, []byte(file.Content), 0o644); err != nil {
+			return err
+		}
+	}
+	for _, command := range plan.Commands {
+		output, err := exec.Command(command.Args[0], command.Args[1:]...).CombinedOutput()
+		if err != nil && !command.IgnoreError {
+			return fmt.Errorf("%s failed: %w: %s", strings.Join(command.Args, " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// String describes the plan as the files and the commands to run
+func (p Plan) String() string {
+	var b strings.Builder
+	for _, file := range p.Files {
+		fmt.Fprintf(&b, "# %s\n%s\n", file.Path, file.Content)
+	}
+	for _, command := range p.Commands {
+		fmt.Fprintf(&b, "$ %s\n", strings.Join(command.Args, " "))
+	}
+	return b.String()
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewPlanName(t *testing.T) {
+	for _, name := range []string{"", "../fast-cli", "fast/cli", `fast\cli`, "fast..cli"} {
+		if _, err := NewPlan(Spec{Name: name, Interval: time.Hour}); err == nil {
+			t.Errorf("NewPlan() with name %q succeeded, want an error", name)
+		}
+	}
+}
+
+func TestSchtasksPlanInterval(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		wantErr  bool
+	}{
+		{30 * time.Minute, false},
+		{48 * time.Hour, false},
+		{90 * time.Second, true},
+		{25 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		_, err := schtasksPlan(Spec{Name: "fast-cli", Executable: `C:\fast-cli.exe`, Interval: tt.interval})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("schtasksPlan() with interval %s error = %v, wantErr %v", tt.interval, err, tt.wantErr)
+		}
+	}
+}
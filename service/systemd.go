@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// systemdPlan installs a user service run by a timer
+func systemdPlan(spec Spec, dir string) Plan {
+	command := []string{systemdQuote(spec.Executable)}
+	for _, arg := range spec.Args {
+		command = append(command, systemdQuote(arg))
+	}
+
+	unit := fmt.Sprintf(`[Unit]
+Description=fast-cli speed test
+
+[Service]
+Type=oneshot
+ExecStart=%s
+`, strings.Join(command, " "))
+
+	timer := fmt.Sprintf(`[Unit]
+Description=Run the fast-cli speed test every %s
+
+[Timer]
+OnBootSec=5min
+OnUnitActiveSec=%ds
+
+[Install]
+WantedBy=timers.target
+`, spec.Interval, int(spec.Interval.Seconds()))
+
+	return Plan{
+		Files: []File{
+			{Path: filepath.Join(dir, spec.Name+".service"), Content: unit},
+			{Path: filepath.Join(dir, spec.Name+".timer"), Content: timer},
+		},
+		Commands: []Command{
+			{Args: []string{"systemctl", "--user", "daemon-reload"}},
+			{Args: []string{"systemctl", "--user", "enable", "--now", spec.Name + ".timer"}},
+		},
+		Note: fmt.Sprintf("Results are logged to the journal, see journalctl --user -u %s. "+
+			"Run loginctl enable-linger to keep the timer running while you are logged out.", spec.Name),
+	}
+}
+
+// systemdQuote quotes an ExecStart argument, escaping the specifiers and
+// variables systemd would otherwise expand
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$").Replace(arg)
+	return `"` + arg + `"`
+}
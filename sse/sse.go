@@ -0,0 +1,106 @@
+// Package sse streams events to HTTP clients as server-sent events
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// clientBuffer is the number of events queued for a client, events for a
+// client that falls further behind are dropped
+const clientBuffer = 64
+
+// Broker publishes events to every connected client
+type Broker struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+	closed  bool
+}
+
+// NewBroker returns a broker without clients
+func NewBroker() *Broker {
+	return &Broker{clients: map[chan []byte]struct{}{}}
+}
+
+// Publish sends data, encoded as JSON, to all clients as an event of the given type
+func (b *Broker) Publish(event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		slog.Debug("could not encode event", "event", event, "error", err)
+		return
+	}
+	message := []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event, payload))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for client := range b.clients {
+		select {
+		case client <- message:
+		default:
+			slog.Debug("dropping event for slow client", "event", event)
+		}
+	}
+}
+
+// Close ends the streams of all clients once their queued events are sent
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for client := range b.clients {
+		close(client)
+		delete(b.clients, client)
+	}
+}
+
+// ServeHTTP streams the published events to the client until it disconnects or the broker is closed
+func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	client := make(chan []byte, clientBuffer)
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		http.Error(w, "no test is running", http.StatusServiceUnavailable)
+		return
+	}
+	b.clients[client] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.clients[client]; ok {
+			close(client)
+			delete(b.clients, client)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case message, ok := <-client:
+			if !ok {
+				return
+			}
+			if _, err := w.Write(message); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
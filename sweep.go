@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mikkelam/fast-cli/utils"
+)
+
+// maxSweepStreams bounds the stream counts accepted by --sweep
+const maxSweepStreams = 64
+
+// SweepLevel is the download speed measured with a fixed number of streams
+type SweepLevel struct {
+	Streams int `json:"streams"`
+	Speed
+}
+
+// parseSweep parses a comma separated list of stream counts such as "1,2,4,8"
+func parseSweep(value string) (levels []int, err error) {
+	for _, field := range strings.Split(value, ",") {
+		streams, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || streams < 1 || streams > maxSweepStreams {
+			return nil, fmt.Errorf("invalid stream count %q in --sweep, expected numbers from 1 to %d", field, maxSweepStreams)
+		}
+		levels = append(levels, streams)
+	}
+	return levels, nil
+}
+
+// measureSweep measures the download speed with each number of streams in turn
+func measureSweep(ctx context.Context, client *http.Client, pool *targetPool, levels []int) ([]SweepLevel, error) {
+	var sweep []SweepLevel
+	for _, streams := range levels {
+		speed, err := measureDownloadSpeed(ctx, client, pool, fmt.Sprintf("%d stream download", streams), streams, false)
+		if err != nil {
+			return nil, fmt.Errorf("with %d streams: %w", streams, err)
+		}
+		sweep = append(sweep, SweepLevel{Streams: streams, Speed: speed})
+	}
+	return sweep, nil
+}
+
+// printSweep writes the sweep as a table of the total and per stream speeds.
+// A total growing with the streams while the per stream speed stays flat
+// points to per connection shaping rather than the capacity of the link.
+func printSweep(w io.Writer, sweep []SweepLevel) {
+	utils.Fprintf(w, "   Streams  Download      Per stream\n")
+	for _, level := range sweep {
+		total := fmt.Sprintf("%-12s", utils.Decimal(level.Speed.Speed, 2)+" "+level.Unit)
+		utils.Fprintf(w, "   %7d  %s  %s\n", level.Streams, thresholds.ColorizeSpeed(level.bitsPerSec, total),
+			strings.TrimSpace(utils.BitsPerSec(level.bitsPerSec/8/float64(level.Streams))))
+	}
+}
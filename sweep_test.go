@@ -0,0 +1,22 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSweep(t *testing.T) {
+	levels, err := parseSweep("1, 2,4,64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 4, 64}; !reflect.DeepEqual(levels, want) {
+		t.Errorf("parseSweep() = %v, want %v", levels, want)
+	}
+
+	for _, value := range []string{"", "0", "65", "1,,2", "two"} {
+		if _, err := parseSweep(value); err == nil {
+			t.Errorf("parseSweep(%q) succeeded, want an error", value)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"mikkelam/fast-cli/provider"
+)
+
+// maxRefreshes bounds how often in a row a single stream may ask for fresh targets
+const maxRefreshes = 3
+
+// targetPool hands out targets to the measurement streams. The signed URLs
+// handed out by fast.com expire, so the pool can swap in fresh targets from the
+// provider while a test is running.
+type targetPool struct {
+	mu         sync.Mutex
+	provider   provider.Provider
+	targets    []provider.Target
+	generation int
+	// refreshing is closed once the running refresh is done, nil without one
+	refreshing chan struct{}
+}
+
+func newTargetPool(p provider.Provider, targets []provider.Target) *targetPool {
+	return &targetPool{provider: p, targets: targets}
+}
+
+// len returns the number of targets in the pool
+func (tp *targetPool) len() int {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return len(tp.targets)
+}
+
+// get returns the target for the given stream and the generation it belongs to
+func (tp *targetPool) get(stream int) (provider.Target, int) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return tp.targets[stream%len(tp.targets)], tp.generation
+}
+
+// snapshot returns a copy of the targets currently in the pool
+func (tp *targetPool) snapshot() []provider.Target {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return append([]provider.Target(nil), tp.targets...)
+}
+
+// refresh fetches new targets from the provider, unless another stream already
+// refreshed the pool since the given generation was handed out. Streams asking
+// while a refresh is running wait for it instead of fetching again. The pool
+// keeps the servers selected by latency, only their urls are replaced.
+func (tp *targetPool) refresh(ctx context.Context, generation int) error {
+	tp.mu.Lock()
+	if generation != tp.generation {
+		tp.mu.Unlock()
+		return nil
+	}
+	if running := tp.refreshing; running != nil {
+		tp.mu.Unlock()
+		select {
+		case <-running:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	tp.refreshing = done
+	selected := tp.targets
+	tp.mu.Unlock()
+
+	// The provider retries for a while, the lock is not held meanwhile so that
+	// the other streams can carry on with their targets
+	slog.Debug("test urls expired, fetching new ones", "provider", tp.provider.Name())
+	targets, err := tp.provider.GetTargets(ctx, candidateServers)
+
+	var kept []provider.Target
+	if err == nil && len(targets) > 0 {
+		kept = keepSelected(selected, targets)
+	}
+
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.refreshing = nil
+	close(done)
+	if err != nil {
+		return err
+	}
+	if len(kept) > 0 {
+		tp.targets = kept
+	}
+	tp.generation++
+	return nil
+}
+
+// keepSelected returns a fresh target for the host of each selected target.
+// Hosts the provider no longer lists are replaced by the first remaining fresh
+// targets. They are not probed, that would add traffic to the measurement.
+func keepSelected(selected []provider.Target, fresh []provider.Target) []provider.Target {
+	used := make([]bool, len(fresh))
+	var kept []provider.Target
+	for _, target := range selected {
+		for i, candidate := range fresh {
+			if !used[i] && targetHost(candidate) == targetHost(target) {
+				used[i] = true
+				kept = append(kept, candidate)
+				break
+			}
+		}
+	}
+	if missing := len(selected) - len(kept); missing > 0 {
+		slog.Debug("selected servers missing from fresh test urls", "missing", missing)
+		for i, candidate := range fresh {
+			if missing == 0 {
+				break
+			}
+			if !used[i] {
+				kept = append(kept, candidate)
+				missing--
+			}
+		}
+	}
+	return kept
+}
+
+// isExpired reports whether the response indicates the target URL is no longer valid
+func isExpired(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// targetHosts returns the distinct hosts of the targets in order
+func targetHosts(targets []provider.Target) (hosts []string) {
+	seen := map[string]bool{}
+	for _, target := range targets {
+		u, err := url.Parse(target.URL)
+		if err != nil || seen[u.Hostname()] {
+			continue
+		}
+		seen[u.Hostname()] = true
+		hosts = append(hosts, u.Hostname())
+	}
+	return
+}
+
+// targetHost returns the host of the target url, or the url itself if it does not parse
+func targetHost(target provider.Target) string {
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return target.URL
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mikkelam/fast-cli/provider"
+)
+
+// refreshProvider hands out fresh targets once release is closed
+type refreshProvider struct {
+	provider.Static
+	targets []provider.Target
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (p *refreshProvider) GetTargets(ctx context.Context, count int) ([]provider.Target, error) {
+	p.calls.Add(1)
+	<-p.release
+	return p.targets, nil
+}
+
+func TestTargetPoolRefresh(t *testing.T) {
+	p := &refreshProvider{
+		targets: []provider.Target{
+			{URL: "https://a.example/speedtest?token=2"},
+			{URL: "https://b.example/speedtest?token=2"},
+			{URL: "https://c.example/speedtest?token=2"},
+		},
+		release: make(chan struct{}),
+	}
+	pool := newTargetPool(p, []provider.Target{
+		{URL: "https://c.example/speedtest?token=1"},
+		{URL: "https://a.example/speedtest?token=1"},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := pool.refresh(context.Background(), 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	// The streams keep their targets while the provider is fetching
+	for p.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	got := make(chan provider.Target)
+	go func() {
+		target, _ := pool.get(0)
+		got <- target
+	}()
+	select {
+	case target := <-got:
+		if target.URL != "https://c.example/speedtest?token=1" {
+			t.Errorf("get() during refresh = %s, want the previous target", target.URL)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("get() blocked while the targets were being fetched")
+	}
+
+	close(p.release)
+	wg.Wait()
+	if calls := p.calls.Load(); calls != 1 {
+		t.Errorf("fetched targets %d times, want once", calls)
+	}
+	want := []provider.Target{
+		{URL: "https://c.example/speedtest?token=2"},
+		{URL: "https://a.example/speedtest?token=2"},
+	}
+	if !reflect.DeepEqual(pool.snapshot(), want) || pool.generation != 1 {
+		t.Errorf("targets after refresh = %v (generation %d), want %v", pool.snapshot(), pool.generation, want)
+	}
+}
+
+func TestTargetPoolRefreshMissingHost(t *testing.T) {
+	// A replacement server must not be probed while the other streams measure
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	var dials atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			dials.Add(1)
+			conn.Close()
+		}
+	}()
+
+	replacement := provider.Target{URL: "http://" + listener.Addr().String() + "/speedtest?token=2"}
+	p := &refreshProvider{
+		targets: []provider.Target{
+			{URL: "https://a.example/speedtest?token=2"},
+			replacement,
+			{URL: "https://d.example/speedtest?token=2"},
+		},
+		release: make(chan struct{}),
+	}
+	close(p.release)
+	pool := newTargetPool(p, []provider.Target{
+		{URL: "https://a.example/speedtest?token=1"},
+		{URL: "https://b.example/speedtest?token=1"},
+	})
+
+	done := make(chan error)
+	go func() { done <- pool.refresh(context.Background(), 0) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("refresh did not finish without probing")
+	}
+
+	want := []provider.Target{{URL: "https://a.example/speedtest?token=2"}, replacement}
+	if !reflect.DeepEqual(pool.snapshot(), want) {
+		t.Errorf("targets after refresh = %v, want %v", pool.snapshot(), want)
+	}
+	if n := dials.Load(); n != 0 {
+		t.Errorf("refresh connected to the replacement server %d times, want none", n)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"mikkelam/fast-cli/provider"
+	"mikkelam/fast-cli/traceroute"
+	"mikkelam/fast-cli/utils"
+)
+
+// traceTimeout bounds the time spent tracing the routes to the test servers
+const traceTimeout = 90 * time.Second
+
+// Trace is the route to one of the test servers
+type Trace struct {
+	Host  string   `json:"host"`
+	Hops  []string `json:"hops,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// traceTargets traces the routes to the distinct hosts of the targets in parallel
+func traceTargets(ctx context.Context, targets []provider.Target) []Trace {
+	hosts := targetHosts(targets)
+	utils.Statusf("%sTracing the route to %d servers...\n", utils.Symbol("🛰️ ", ""), len(hosts))
+	ctx, cancel := context.WithTimeout(ctx, traceTimeout)
+	defer cancel()
+
+	traces := make([]Trace, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			traces[i].Host = host
+			hops, err := traceroute.Route(ctx, host)
+			if err != nil {
+				traces[i].Error = err.Error()
+			}
+			traces[i].Hops = hops
+		}()
+	}
+	wg.Wait()
+	return traces
+}
@@ -0,0 +1,49 @@
+package traceroute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+// MaxHops is the highest TTL probed
+const MaxHops = 30
+
+// Route traces the path to host with the system traceroute command and returns
+// one line per hop, as printed by that command
+func Route(ctx context.Context, host string) ([]string, error) {
+	var missing []string
+	for _, command := range commands(host) {
+		output, err := exec.CommandContext(ctx, command[0], command[1:]...).Output()
+		if errors.Is(err, exec.ErrNotFound) {
+			missing = append(missing, command[0])
+			continue
+		}
+		// Unreachable hops make some implementations exit with an error, the
+		// hops printed until then are still worth showing
+		if lines := hops(string(output)); len(lines) > 0 {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s failed: %w", command[0], err)
+		}
+		return nil, fmt.Errorf("%s printed no hops", command[0])
+	}
+	return nil, fmt.Errorf("no traceroute command found, install %s", strings.Join(missing, " or "))
+}
+
+// hops keeps the lines of the output that start with a hop number, dropping
+// headers and footers
+func hops(output string) (lines []string) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && unicode.IsDigit(rune(trimmed[0])) {
+			lines = append(lines, line)
+		}
+	}
+	return
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package traceroute
+
+import "strconv"
+
+// commands lists the traceroute commands to try, tracepath is often installed
+// on Linux systems that lack traceroute
+func commands(host string) [][]string {
+	maxHops := strconv.Itoa(MaxHops)
+	return [][]string{
+		{"traceroute", "-n", "-q", "1", "-w", "2", "-m", maxHops, host},
+		{"tracepath", "-n", "-m", maxHops, host},
+	}
+}
@@ -0,0 +1,10 @@
+package traceroute
+
+import "strconv"
+
+// commands lists the traceroute commands to try
+func commands(host string) [][]string {
+	return [][]string{
+		{"tracert", "-d", "-w", "2000", "-h", strconv.Itoa(MaxHops), host},
+	}
+}
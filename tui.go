@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"mikkelam/fast-cli/utils"
+)
+
+// Terminal control sequences used by the TUI
+const (
+	enterAltScreen = "\033[?1049h\033[?25l"
+	exitAltScreen  = "\033[?25h\033[?1049l"
+	cursorHome     = "\033[H"
+	clearLine      = "\033[K"
+	clearBelow     = "\033[J"
+	graphHeight    = 8
+)
+
+// tuiDisplay draws a full screen view with live throughput graphs and per-stream bars
+type tuiDisplay struct {
+	measurements []*measurement
+	latency      time.Duration
+	active       bool
+}
+
+func newTUIDisplay() *tuiDisplay {
+	return &tuiDisplay{}
+}
+
+func (d *tuiDisplay) start(m *measurement) {
+	d.measurements = append(d.measurements, m)
+	if d.active {
+		return
+	}
+	d.active = true
+	// Ctrl+C cancels the test, which leaves the alternate screen through close
+	utils.Statusf(enterAltScreen)
+}
+
+func (d *tuiDisplay) showLatency(latency time.Duration) {
+	d.latency = latency
+}
+
+func (d *tuiDisplay) update(m *measurement, elapsed time.Duration, fraction float64) {
+	width := utils.TerminalWidth(os.Stderr)
+	lines := []string{
+		utils.Colorize(utils.ColorBold, fmt.Sprintf("fast-cli %s", version)) +
+			fmt.Sprintf("  %s %ss / %ss  [%s]", m.direction, utils.Decimal(elapsed.Seconds(), 1),
+				utils.Decimal(maxDuration.Seconds(), 1), utils.Bar(fraction, 20)),
+		"",
+	}
+
+	if d.latency > 0 {
+		lines = append(lines, fmt.Sprintf("Latency   %s ms", utils.Decimal(milliseconds(d.latency), 1)), "")
+	}
+
+	for _, measured := range d.measurements {
+		bitsPerSec := measured.meter.Bandwidth() * 8
+		title := fmt.Sprintf("%-9s %s", strings.ToUpper(measured.direction[:1])+measured.direction[1:],
+			thresholds.ColorizeSpeed(bitsPerSec, strings.TrimSpace(utils.BitsPerSec(bitsPerSec/8))))
+		lines = append(lines, title)
+		for _, row := range utils.Graph(measured.samples, width-2, graphHeight) {
+			lines = append(lines, utils.Symbol("│", "|")+row)
+		}
+		lines = append(lines, utils.Symbol("└", "+")+strings.Repeat(utils.Symbol("─", "-"), max(width-2, 0)), "")
+	}
+
+	lines = append(lines, "Streams")
+	lines = append(lines, d.streamLines(m, width)...)
+	lines = append(lines, "", "Press Ctrl+C to abort")
+
+	utils.Statusf("%s%s%s%s", cursorHome, strings.Join(lines, clearLine+"\n"), clearLine, clearBelow)
+}
+
+// streamLines renders one bar per stream, scaled to the fastest stream
+func (d *tuiDisplay) streamLines(m *measurement, width int) (lines []string) {
+	fastest := 0.0
+	for _, stream := range m.streams {
+		fastest = max(fastest, stream.Bandwidth())
+	}
+
+	barWidth := max(width-30, 10)
+	for i, stream := range m.streams {
+		fraction := 0.0
+		if fastest > 0 {
+			fraction = stream.Bandwidth() / fastest
+		}
+		lines = append(lines, fmt.Sprintf(" #%-2d [%s] %s", i+1, utils.Bar(fraction, barWidth),
+			utils.BitsPerSec(stream.Bandwidth())))
+	}
+	return
+}
+
+func (d *tuiDisplay) finish(m *measurement) {}
+
+func (d *tuiDisplay) close() {
+	if !d.active {
+		return
+	}
+	d.active = false
+	utils.Statusf(exitAltScreen)
+}
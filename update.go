@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"mikkelam/fast-cli/utils"
+)
+
+// latestReleaseURL is the GitHub api endpoint describing the newest release
+const latestReleaseURL = "https://api.github.com/repos/mikkelam/fast-cli/releases/latest"
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+func printVersion(c *cli.Context) error {
+	if err := initApputils(); err != nil {
+		return err
+	}
+
+	utils.Printf("fast-cli version %s\n", displayVersion)
+	if !c.Bool("check") {
+		return nil
+	}
+
+	release, err := getLatestRelease(c.Context)
+	if err != nil {
+		return withCodef(codeUpdateCheckFailed, "could not check for updates: %w", err)
+	}
+
+	switch newer, ok := isNewerVersion(release.TagName, version); {
+	case !ok:
+		utils.Printf("Latest release is %s, see %s\n", release.TagName, release.HTMLURL)
+	case newer:
+		utils.Printf("A newer version %s is available, see %s\n", release.TagName, release.HTMLURL)
+	default:
+		utils.Println("You are running the latest version")
+	}
+	return nil
+}
+
+func getLatestRelease(ctx context.Context) (release githubRelease, err error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	request, err := http.NewRequestWithContext(ctx, "GET", latestReleaseURL, nil)
+	if err != nil {
+		return release, err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("User-Agent", displayVersion)
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return release, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return release, fmt.Errorf("github returned %s", resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&release)
+	return
+}
+
+// isNewerVersion reports whether latest is a newer version than current. The
+// second result is false when either is not a release version, such as "dev".
+func isNewerVersion(latest string, current string) (newer bool, ok bool) {
+	latestParts, latestOk := parseVersion(latest)
+	currentParts, currentOk := parseVersion(current)
+	if !latestOk || !currentOk {
+		return false, false
+	}
+
+	for i := range latestParts {
+		if latestParts[i] != currentParts[i] {
+			return latestParts[i] > currentParts[i], true
+		}
+	}
+	return false, true
+}
+
+// parseVersion parses "v1.2.3" or "1.2.3-rc1" into its numeric components
+func parseVersion(text string) (parts [3]int, ok bool) {
+	text = strings.TrimPrefix(text, "v")
+	text, _, _ = strings.Cut(text, "-")
+
+	fields := strings.Split(text, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = number
+	}
+	return parts, true
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestIsNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		newer, ok       bool
+	}{
+		{"v1.2.4", "1.2.3", true, true},
+		{"v1.10.0", "1.9.9", true, true},
+		{"v2.0.0", "1.99.99", true, true},
+		{"v1.2.3", "1.2.3", false, true},
+		{"v1.2.3", "1.3.0", false, true},
+		{"v1.3.0-rc1", "1.2.3", true, true},
+		{"v1.2.3", "dev", false, false},
+		{"latest", "1.2.3", false, false},
+		{"v1.2", "1.2.3", false, false},
+	}
+	for _, tt := range tests {
+		newer, ok := isNewerVersion(tt.latest, tt.current)
+		if newer != tt.newer || ok != tt.ok {
+			t.Errorf("isNewerVersion(%q, %q) = %v, %v, want %v, %v", tt.latest, tt.current, newer, ok, tt.newer, tt.ok)
+		}
+	}
+}
@@ -1,22 +1,40 @@
 package utils
 
-import "time"
+import (
+	"sync/atomic"
+	"time"
+)
+
+// epoch anchors the meter timestamps so they are taken from the monotonic clock
+var epoch = time.Now()
 
 // BandwidthMeter counts the number of bytes written to it over time.
+// It is safe for concurrent use by multiple goroutines and must not be copied.
 type BandwidthMeter struct {
-	bytesRead uint64
-	start     time.Time
-	lastRead  time.Time
+	bytesRead atomic.Uint64
+	start     atomic.Int64 // nanoseconds since epoch, 0 if not started
+	lastRead  atomic.Int64 // nanoseconds since epoch
+}
+
+func now() int64 {
+	return int64(time.Since(epoch))
 }
 
 // Write implements the io.Writer interface.
 func (br *BandwidthMeter) Write(p []byte) (int, error) {
 	// Always completes and never returns an error.
-	br.lastRead = time.Now().UTC()
 	n := len(p)
-	br.bytesRead += uint64(n)
-	if br.start.IsZero() {
-		br.start = br.lastRead
+	br.bytesRead.Add(uint64(n))
+
+	timestamp := now()
+	br.start.CompareAndSwap(0, timestamp)
+
+	// Concurrent writers may race here, only ever move the timestamp forward
+	for {
+		last := br.lastRead.Load()
+		if timestamp <= last || br.lastRead.CompareAndSwap(last, timestamp) {
+			break
+		}
 	}
 
 	return n, nil
@@ -24,24 +42,27 @@ func (br *BandwidthMeter) Write(p []byte) (int, error) {
 
 // Start records the start time
 func (br *BandwidthMeter) Start() {
-	br.start = time.Now().UTC()
+	br.start.Store(now())
 }
 
 // Bandwidth returns the current bandwidth
 func (br *BandwidthMeter) Bandwidth() (bytesPerSec float64) {
-	deltaSecs := br.lastRead.Sub(br.start).Seconds()
-	bytesPerSec = float64(br.bytesRead) / deltaSecs
+	deltaSecs := br.Duration().Seconds()
+	if deltaSecs <= 0 {
+		return 0
+	}
+	bytesPerSec = float64(br.bytesRead.Load()) / deltaSecs
 	return
 }
 
 // BytesRead returns the number of bytes read by this BandwidthMeter
 func (br *BandwidthMeter) BytesRead() (bytes uint64) {
-	bytes = br.bytesRead
+	bytes = br.bytesRead.Load()
 	return
 }
 
-// Duration returns the current duration
+// Duration returns the time from the start to the last write, 0 before the first write
 func (br *BandwidthMeter) Duration() (duration time.Duration) {
-	duration = br.lastRead.Sub(br.start)
+	duration = max(time.Duration(br.lastRead.Load()-br.start.Load()), 0)
 	return
 }
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBandwidthMeterConcurrentWriters(t *testing.T) {
+	const writers, writes, size = 8, 1000, 1500
+	var meter BandwidthMeter
+	meter.Start()
+
+	var wg sync.WaitGroup
+	buf := make([]byte, size)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				if n, err := meter.Write(buf); n != size || err != nil {
+					t.Errorf("Write() = %d, %v, want %d, nil", n, err, size)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := meter.BytesRead(), uint64(writers*writes*size); got != want {
+		t.Errorf("BytesRead() = %d, want %d", got, want)
+	}
+	if meter.Duration() < 0 {
+		t.Errorf("Duration() = %s, want >= 0", meter.Duration())
+	}
+}
+
+func TestBandwidthMeterBeforeWrite(t *testing.T) {
+	var unstarted BandwidthMeter
+	started := &BandwidthMeter{}
+	started.Start()
+	time.Sleep(time.Millisecond)
+
+	for name, meter := range map[string]*BandwidthMeter{"unstarted": &unstarted, "started": started} {
+		if d := meter.Duration(); d != 0 {
+			t.Errorf("%s: Duration() = %s, want 0", name, d)
+		}
+		if bw := meter.Bandwidth(); bw != 0 {
+			t.Errorf("%s: Bandwidth() = %f, want 0", name, bw)
+		}
+		if n := meter.BytesRead(); n != 0 {
+			t.Errorf("%s: BytesRead() = %d, want 0", name, n)
+		}
+	}
+}
+
+func TestBandwidthMeterBandwidth(t *testing.T) {
+	var meter BandwidthMeter
+	meter.Start()
+	meter.Write(make([]byte, 1000))
+	time.Sleep(10 * time.Millisecond)
+	meter.Write(make([]byte, 1000))
+
+	d := meter.Duration()
+	if d < 10*time.Millisecond {
+		t.Fatalf("Duration() = %s, want at least 10ms", d)
+	}
+	if got, want := meter.Bandwidth(), 2000/d.Seconds(); got != want {
+		t.Errorf("Bandwidth() = %f, want %f", got, want)
+	}
+}
+
+func BenchmarkBandwidthMeterWriteParallel(b *testing.B) {
+	var meter BandwidthMeter
+	meter.Start()
+	buf := make([]byte, 32*1024)
+	b.SetBytes(int64(len(buf)))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			meter.Write(buf)
+		}
+	})
+}
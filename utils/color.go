@@ -0,0 +1,43 @@
+package utils
+
+// ANSI color codes
+const (
+	ColorReset  = "\033[0m"
+	ColorRed    = "\033[31m"
+	ColorGreen  = "\033[32m"
+	ColorYellow = "\033[33m"
+	ColorBold   = "\033[1m"
+)
+
+// Colorize wraps text in the given color when colors are enabled
+func Colorize(color string, text string) string {
+	if !AppConfig.Color {
+		return text
+	}
+	return color + text + ColorReset
+}
+
+// SpeedThresholds decide the color a speed is shown in
+type SpeedThresholds struct {
+	// Good is the bits per second at or above which a speed is shown green
+	Good float64
+	// Poor is the bits per second below which a speed is shown red, speeds in between are yellow
+	Poor float64
+}
+
+// Color returns the color for a speed in bits per second
+func (t SpeedThresholds) Color(bitsPerSec float64) string {
+	switch {
+	case bitsPerSec >= t.Good:
+		return ColorGreen
+	case bitsPerSec < t.Poor:
+		return ColorRed
+	default:
+		return ColorYellow
+	}
+}
+
+// ColorizeSpeed colors text according to the speed it describes
+func (t SpeedThresholds) ColorizeSpeed(bitsPerSec float64, text string) string {
+	return Colorize(t.Color(bitsPerSec), text)
+}
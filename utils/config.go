@@ -1,8 +1,15 @@
 package utils
 
 type Config struct {
-	Debug      bool
 	JsonOutput bool
+	// Color enables ANSI colors in the output
+	Color bool
+	// Quiet suppresses progress and status messages
+	Quiet bool
+	// ASCII replaces emoji, spinners and block characters the terminal cannot display
+	ASCII bool
+	// DecimalComma writes decimals in text output with a comma, JSON is unaffected
+	DecimalComma bool
 }
 
 var AppConfig = &Config{}
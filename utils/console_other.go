@@ -0,0 +1,16 @@
+//go:build !windows
+
+package utils
+
+import "os"
+
+// EnableVirtualTerminal reports whether f handles ANSI escape sequences, which
+// terminals outside of Windows always do
+func EnableVirtualTerminal(f *os.File) bool {
+	return true
+}
+
+// UnicodeConsole reports whether the terminal can display emoji and box drawing characters
+func UnicodeConsole() bool {
+	return true
+}
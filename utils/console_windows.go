@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// utf8CodePage is the Windows code page identifier of UTF-8
+const utf8CodePage = 65001
+
+var getConsoleOutputCP = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetConsoleOutputCP")
+
+// EnableVirtualTerminal turns on processing of ANSI escape sequences for the
+// console f writes to, and reports whether the console handles them
+func EnableVirtualTerminal(f *os.File) bool {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
+
+// UnicodeConsole reports whether the console can display emoji and box drawing
+// characters. Windows Terminal and VS Code can, the legacy console only with the
+// UTF-8 code page.
+func UnicodeConsole() bool {
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("TERM_PROGRAM") != "" {
+		return true
+	}
+	if getConsoleOutputCP.Find() != nil {
+		return false
+	}
+	codePage, _, _ := getConsoleOutputCP.Call()
+	return codePage == utf8CodePage
+}
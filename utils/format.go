@@ -3,6 +3,8 @@ package utils
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 
 	"github.com/dustin/go-humanize"
 )
@@ -10,7 +12,7 @@ import (
 // BitsPerSec formats a byte count
 func BitsPerSec(bytes float64) string {
 	prettySize, prettyUnit := humanize.ComputeSI(bytes * 8)
-	return fmt.Sprintf("%7.2f %sbps", prettySize, prettyUnit)
+	return fmt.Sprintf("%7s %sbps", Decimal(prettySize, 2), prettyUnit)
 }
 
 func BitsPerSecWithUnit(bytes float64) (float64, string) {
@@ -30,3 +32,39 @@ func Bytes(bytes uint64) string {
 func Percent(current uint64, total uint64) string {
 	return fmt.Sprintf("%4.1f%%", float64(current)/float64(total)*100)
 }
+
+// ParseBitRate parses a rate such as "200Mbps", "1.5Gbps" or "500k" into bits per second
+func ParseBitRate(rate string) (float64, error) {
+	text := strings.TrimSpace(rate)
+	lower := strings.ToLower(text)
+	for _, suffix := range []string{"bit/s", "bps"} {
+		if strings.HasSuffix(lower, suffix) {
+			text = text[:len(text)-len(suffix)]
+			lower = lower[:len(lower)-len(suffix)]
+			break
+		}
+	}
+
+	multiplier := 1.0
+	if lower != "" {
+		switch lower[len(lower)-1] {
+		case 'k':
+			multiplier = 1e3
+		case 'm':
+			multiplier = 1e6
+		case 'g':
+			multiplier = 1e9
+		case 't':
+			multiplier = 1e12
+		}
+		if multiplier != 1 {
+			text = text[:len(text)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid rate %q, expected e.g. 200Mbps", rate)
+	}
+	return value * multiplier, nil
+}
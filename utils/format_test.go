@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestParseBitRate(t *testing.T) {
+	tests := []struct {
+		rate string
+		want float64
+	}{
+		{"200Mbps", 200e6},
+		{"1.5Gbps", 1.5e9},
+		{"500k", 500e3},
+		{"100 mbit/s", 100e6},
+		{" 2T ", 2e12},
+		{"42", 42},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParseBitRate(tt.rate)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseBitRate(%q) = %v, %v, want %v", tt.rate, got, err, tt.want)
+		}
+	}
+
+	for _, rate := range []string{"", "Mbps", "fast", "-5Mbps", "10x"} {
+		if _, err := ParseBitRate(rate); err == nil {
+			t.Errorf("ParseBitRate(%q) succeeded, want an error", rate)
+		}
+	}
+}
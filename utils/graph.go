@@ -0,0 +1,94 @@
+package utils
+
+import "strings"
+
+// blocks are the eighths used to draw bars, from empty to full
+var blocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// asciiBlocks stand in for blocks on terminals without Unicode support
+var asciiBlocks = []rune(" .,:-=+*#")
+
+// levels returns the characters for zero to eight eighths of a cell
+func levels() []rune {
+	if AppConfig.ASCII {
+		return asciiBlocks
+	}
+	return blocks
+}
+
+// Graph renders the most recent samples as a bar chart of the given size. Each
+// column is one sample, scaled so the largest visible sample fills the height.
+func Graph(samples []float64, width int, height int) []string {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+
+	peak := 0.0
+	for _, sample := range samples {
+		peak = max(peak, sample)
+	}
+
+	levels := levels()
+	rows := make([]string, height)
+	for row := range rows {
+		var line strings.Builder
+		// Eighths of a cell that lie below this row
+		floor := float64((height - 1 - row) * 8)
+		for _, sample := range samples {
+			level := 0.0
+			if peak > 0 {
+				level = sample/peak*float64(height*8) - floor
+			}
+			line.WriteRune(levels[int(min(max(level, 0), 8))])
+		}
+		line.WriteString(strings.Repeat(" ", width-len(samples)))
+		rows[row] = line.String()
+	}
+	return rows
+}
+
+// Bar renders a horizontal bar of the given width filled to fraction
+func Bar(fraction float64, width int) string {
+	fraction = min(max(fraction, 0), 1)
+	filled := int(fraction * float64(width))
+	return strings.Repeat(Symbol("█", "#"), filled) + strings.Repeat(Symbol("░", "-"), width-filled)
+}
+
+// Sparkline renders samples as a single line of at most width characters.
+// When there are more samples than columns, neighbouring samples are averaged.
+func Sparkline(samples []float64, width int) string {
+	if len(samples) == 0 || width <= 0 {
+		return ""
+	}
+
+	columns := make([]float64, min(len(samples), width))
+	for i := range columns {
+		from := i * len(samples) / len(columns)
+		to := (i + 1) * len(samples) / len(columns)
+		sum := 0.0
+		for _, sample := range samples[from:to] {
+			sum += sample
+		}
+		columns[i] = sum / float64(to-from)
+	}
+
+	peak := 0.0
+	for _, column := range columns {
+		peak = max(peak, column)
+	}
+
+	levels := levels()
+	var line strings.Builder
+	for _, column := range columns {
+		// Always draw at least the lowest block so the line keeps its shape
+		level := 1
+		if peak > 0 {
+			level = max(int(column/peak*8+0.5), 1)
+		}
+		line.WriteRune(levels[level])
+	}
+	return line.String()
+}
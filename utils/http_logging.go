@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+// LoggingTransport dumps requests and response headers to the debug log
+type LoggingTransport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		return t.Base.RoundTrip(req)
+	}
+
+	if dump, err := httputil.DumpRequestOut(req, false); err == nil {
+		slog.Debug("http request", "dump", string(dump))
+	}
+
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		slog.Debug("http request failed", "url", req.URL.String(), "duration", time.Since(start), "error", err)
+		return resp, err
+	}
+
+	if dump, err := httputil.DumpResponse(resp, false); err == nil {
+		slog.Debug("http response", "url", req.URL.String(), "duration", time.Since(start), "dump", string(dump))
+	}
+	return resp, nil
+}
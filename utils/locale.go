@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// commaLanguages are the languages whose numbers use a decimal comma
+var commaLanguages = map[string]bool{
+	"az": true, "be": true, "bg": true, "ca": true, "cs": true, "da": true, "de": true, "el": true,
+	"es": true, "et": true, "eu": true, "fi": true, "fr": true, "gl": true, "hr": true, "hu": true,
+	"id": true, "is": true, "it": true, "kk": true, "lt": true, "lv": true, "nb": true, "nl": true,
+	"nn": true, "no": true, "pl": true, "pt": true, "ro": true, "ru": true, "sk": true, "sl": true,
+	"sq": true, "sr": true, "sv": true, "tr": true, "uk": true, "vi": true,
+}
+
+// localeExceptions are regions that do not follow the separator of their language
+var localeExceptions = map[string]bool{
+	"de_ch": false, "de_li": false, "it_ch": false, "es_mx": false, "es_us": false, "en_za": true,
+}
+
+// DecimalComma reports whether locale, e.g. "de_DE.UTF-8" or "pt-BR", writes
+// numbers with a decimal comma. "system" uses the locale from the environment.
+func DecimalComma(locale string) bool {
+	if locale == "system" {
+		locale = systemLocale()
+	}
+	// Strip the encoding and modifier, e.g. ".UTF-8" or "@euro"
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.ToLower(strings.ReplaceAll(locale, "-", "_"))
+
+	if comma, ok := localeExceptions[locale]; ok {
+		return comma
+	}
+	language, _, _ := strings.Cut(locale, "_")
+	return commaLanguages[language]
+}
+
+// systemLocale returns the locale used for numbers, following the POSIX precedence
+func systemLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Decimal formats v with prec decimals and the decimal separator of the chosen locale
+func Decimal(v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	if AppConfig.DecimalComma {
+		return strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LogOptions configures the diagnostic logger
+type LogOptions struct {
+	// Level is the minimum level that is logged
+	Level slog.Level
+	// File receives JSON formatted logs instead of stderr when set
+	File string
+	// MaxSize is the size in bytes at which the log file is rotated
+	MaxSize int64
+	// MaxBackups is the number of rotated log files kept around
+	MaxBackups int
+}
+
+// ParseLogLevel parses a level name such as "debug" or "warn"
+func ParseLogLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToUpper(name))); err != nil {
+		return level, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// ConfigureLogging installs the default slog logger. The returned closer must be
+// closed before exiting to flush the log file.
+func ConfigureLogging(opts LogOptions) (io.Closer, error) {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+
+	if opts.File == "" {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, handlerOpts)))
+		return io.NopCloser(nil), nil
+	}
+
+	file, err := OpenRotatingFile(opts.File, opts.MaxSize, opts.MaxBackups)
+	if err != nil {
+		return nil, fmt.Errorf("could not open log file: %w", err)
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(file, handlerOpts)))
+	return file, nil
+}
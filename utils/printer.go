@@ -12,15 +12,10 @@ func PrintJSON(format string, a ...any) {
 	}
 }
 
-func Debugln(a ...any) {
-	if AppConfig.Debug {
-		fmt.Println(a...)
-	}
-}
-
-func Debugf(format string, a ...any) {
-	if AppConfig.Debug {
-		fmt.Printf(format, a...)
+// FprintJSON writes JSON output to w, it is a no-op unless JSON output is enabled
+func FprintJSON(w io.Writer, format string, a ...any) {
+	if AppConfig.JsonOutput {
+		fmt.Fprintf(w, format, a...)
 	}
 }
 
@@ -48,6 +43,20 @@ func Print(a ...any) {
 	}
 }
 
+// Statusln writes progress and status messages to stderr, keeping stdout for results
+func Statusln(a ...any) {
+	if !AppConfig.JsonOutput && !AppConfig.Quiet {
+		fmt.Fprintln(os.Stderr, a...)
+	}
+}
+
+// Statusf writes progress and status messages to stderr, keeping stdout for results
+func Statusf(format string, a ...any) {
+	if !AppConfig.JsonOutput && !AppConfig.Quiet {
+		fmt.Fprintf(os.Stderr, format, a...)
+	}
+}
+
 func Errorln(a ...any) {
 	fmt.Fprintln(os.Stderr, a...)
 }
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// minBarWidth is the narrowest bar worth drawing, below it only the numbers are shown
+const minBarWidth = 10
+
+// TerminalWidth returns the width of the terminal f is connected to. It is cheap
+// enough to call on every redraw, which keeps the output correct across resizes.
+func TerminalWidth(f *os.File) int {
+	if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 80
+}
+
+// ProgressLine is the state shown on the single line progress display
+type ProgressLine struct {
+	Spinner string
+	// Fraction is the completed part of the test, from 0 to 1
+	Fraction float64
+	Elapsed  time.Duration
+	Bytes    uint64
+	// BitsPerSec is the current speed
+	BitsPerSec float64
+	Thresholds SpeedThresholds
+	// Done marks a test that completed, it is shown instead of the percentage
+	Done bool
+}
+
+// Render formats the line to fit within width columns, dropping the bar on narrow terminals
+func (p ProgressLine) Render(width int) string {
+	fraction := min(max(p.Fraction, 0), 1)
+	speed := BitsPerSec(p.BitsPerSec / 8)
+	status := fmt.Sprintf("%3.0f%%", fraction*100)
+	if p.Done {
+		status = "done"
+	}
+	info := fmt.Sprintf("%s %5ss %s %s", status, Decimal(p.Elapsed.Seconds(), 1), Bytes(p.Bytes), speed)
+	coloredInfo := strings.Replace(info, speed, p.Thresholds.ColorizeSpeed(p.BitsPerSec, speed), 1)
+
+	// Leave the last column free so the cursor never wraps onto a new line
+	available := width - 1 - utf8.RuneCountInString(p.Spinner) - 1 - utf8.RuneCountInString(info)
+	barWidth := available - 3 // brackets and the separating space
+	if barWidth < minBarWidth {
+		line := p.Spinner + " " + coloredInfo
+		if utf8.RuneCountInString(p.Spinner+" "+info) >= width {
+			return p.Spinner + " " + p.Thresholds.ColorizeSpeed(p.BitsPerSec, strings.TrimSpace(speed))
+		}
+		return line
+	}
+
+	return fmt.Sprintf("%s [%s] %s", p.Spinner, Bar(fraction, barWidth), coloredInfo)
+}
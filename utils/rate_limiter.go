@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that delays writes to keep the throughput of
+// everything written to it below a rate. It is safe for concurrent use, writers
+// sharing a limiter share its rate.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter allowing bytesPerSec bytes per second, with
+// bursts of up to a tenth of a second worth of data
+func NewRateLimiter(bytesPerSec float64) *RateLimiter {
+	burst := max(bytesPerSec/10, 64*1024)
+	return &RateLimiter{rate: bytesPerSec, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// Write implements the io.Writer interface, it blocks until len(p) bytes are allowed
+func (l *RateLimiter) Write(p []byte) (int, error) {
+	l.Wait(len(p))
+	return len(p), nil
+}
+
+// Wait blocks until n more bytes may be transferred
+func (l *RateLimiter) Wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	// Take the tokens right away, concurrent writers queue up behind the debt
+	l.tokens -= float64(n)
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	time.Sleep(wait)
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// IsTerminal reports whether f is connected to a terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// IsDumbTerminal reports whether the terminal cannot handle cursor movement
+func IsDumbTerminal() bool {
+	return os.Getenv("TERM") == "dumb"
+}
+
+// NoColorRequested reports whether the user opted out of color, see https://no-color.org
+func NoColorRequested() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// Symbol returns fancy, or plain when the terminal only handles ASCII
+func Symbol(fancy, plain string) string {
+	if AppConfig.ASCII {
+		return plain
+	}
+	return fancy
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportOptions tunes the HTTP transport shared by the test streams
+type TransportOptions struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept open per server
+	MaxIdleConnsPerHost int
+	// DisableKeepAlives opens a new connection for every request
+	DisableKeepAlives bool
+	// DialTimeout bounds establishing a connection, zero means no limit
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout bounds the TLS handshake, zero means no limit
+	TLSHandshakeTimeout time.Duration
+	// BufferSize is the size of the per connection read and write buffers
+	BufferSize int
+}
+
+// DefaultTransportOptions returns the options used for speed tests
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		MaxIdleConnsPerHost: 16,
+		DialTimeout:         10 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		BufferSize:          64 * 1024,
+	}
+}
+
+// NewTransport returns a transport for throughput tests. Compression is disabled
+// so the payloads are transferred as they are, and HTTP/2 is disabled so that
+// every stream gets a TCP connection of its own instead of being multiplexed.
+func NewTransport(opts TransportOptions) *http.Transport {
+	dialer := &net.Dialer{Timeout: opts.DialTimeout, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+		DisableCompression:    true,
+		DisableKeepAlives:     opts.DisableKeepAlives,
+		ReadBufferSize:        opts.BufferSize,
+		WriteBufferSize:       opts.BufferSize,
+		TLSNextProto:          map[string]func(string, *tls.Conn) http.RoundTripper{},
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Download speeds Netflix recommends per stream for each quality
+const (
+	uhdBitrate = 15e6
+	hdBitrate  = 5e6
+	sdBitrate  = 3e6
+)
+
+// highLatency is the latency above which playback is noticeably slow to start
+const highLatency = 200 * time.Millisecond
+
+// Verdict describes what the connection can stream
+type Verdict struct {
+	// Quality is the best quality the connection can stream: 4k, 1080p, sd or none
+	Quality string `json:"quality"`
+	// Streams is the number of simultaneous streams at that quality
+	Streams int    `json:"streams"`
+	Summary string `json:"summary"`
+}
+
+// streamingVerdict translates the measured download speed and latency into
+// what a non-technical user cares about: can I stream, and at what quality
+func streamingVerdict(downloadBitsPerSec float64, latency time.Duration) Verdict {
+	var verdict Verdict
+	switch {
+	case downloadBitsPerSec >= uhdBitrate:
+		verdict = Verdict{Quality: "4k", Streams: int(downloadBitsPerSec / uhdBitrate)}
+	case downloadBitsPerSec >= hdBitrate:
+		verdict = Verdict{Quality: "1080p", Streams: int(downloadBitsPerSec / hdBitrate)}
+	case downloadBitsPerSec >= sdBitrate:
+		verdict = Verdict{Quality: "sd", Streams: int(downloadBitsPerSec / sdBitrate)}
+	default:
+		verdict = Verdict{Quality: "none"}
+	}
+
+	switch {
+	case verdict.Quality == "none":
+		verdict.Summary = "Too slow for reliable video streaming"
+	case verdict.Quality == "4k" && verdict.Streams > 1:
+		verdict.Summary = fmt.Sprintf("4K: yes, %d streams at once", verdict.Streams)
+	case verdict.Quality == "4k":
+		verdict.Summary = "4K: yes, one stream at a time"
+	case verdict.Quality == "1080p":
+		verdict.Summary = "1080p only, no 4K"
+	default:
+		verdict.Summary = "Standard definition only"
+	}
+
+	if latency >= highLatency {
+		verdict.Summary += ", expect slow starts due to high latency"
+	}
+	return verdict
+}